@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const USAGE = "Usage: `gram [+LINE] <FILENAME>[:LINE[:COL]]`"
+
+// ParseArgs of gram's command line: a file, either preceded by "+LINE[:COL]" ("+" alone is the last line), or
+// followed by ":LINE[:COL]", and an optional ':' as in compiler errors (i.e. "main.go:12:5:"). Files that exist are
+// opened as named, even if they contain a position. Returns the file, and the target to Goto ("" for none).
+func ParseArgs(args []string) (string, string, error) {
+	target := ""
+	if len(args) > 0 && strings.HasPrefix(args[0], "+") {
+		target = strings.TrimPrefix(args[0], "+")
+		if len(target) == 0 {
+			target = "100%"
+		}
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		return "", "", fmt.Errorf("expected one file. %s", USAGE)
+	}
+
+	filename := args[0]
+	if _, err := os.Stat(filename); err == nil || len(target) > 0 {
+		return filename, target, nil
+	}
+	filename, target = splitPosition(filename)
+	return filename, target, nil
+}
+
+// splitPosition of "file:LINE[:COL][:]" into the file and "LINE[:COL]". Names without a position are returned as is.
+func splitPosition(name string) (string, string) {
+	parts := strings.Split(strings.TrimSuffix(name, ":"), ":")
+	n := 0 // Trailing numeric parts, at most LINE and COL
+	for i := len(parts) - 1; i > 0 && n < 2; i-- {
+		if v, err := strconv.Atoi(parts[i]); err != nil || v < 1 {
+			break
+		}
+		n++
+	}
+	if n == 0 {
+		return name, ""
+	}
+	return strings.Join(parts[:len(parts)-n], ":"), strings.Join(parts[len(parts)-n:], ":")
+}
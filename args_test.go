@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "odd:12")
+	if err := os.WriteFile(existing, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type testParam struct {
+		description string
+		args        []string
+		filename    string
+		target      string
+		valid       bool
+	}
+	tests := []testParam{
+		{"file", []string{"main.go"}, "main.go", "", true},
+		{"line", []string{"main.go:120"}, "main.go", "120", true},
+		{"line and column", []string{"main.go:120:5"}, "main.go", "120:5", true},
+		{"compiler error", []string{"./pkg/main.go:12:5:"}, "./pkg/main.go", "12:5", true},
+		{"grep match", []string{"main.go:7:"}, "main.go", "7", true},
+		{"only the last two numbers", []string{"a:1:2:3"}, "a:1", "2:3", true},
+		{"colon in the name", []string{"a:b.go"}, "a:b.go", "", true},
+		{"zero line", []string{"main.go:0"}, "main.go:0", "", true},
+		{"plus line", []string{"+120", "main.go"}, "main.go", "120", true},
+		{"plus line and column", []string{"+120:5", "main.go"}, "main.go", "120:5", true},
+		{"plus alone", []string{"+", "main.go"}, "main.go", "100%", true},
+		{"existing file with a colon", []string{existing}, existing, "", true},
+		{"no file", []string{"+120"}, "", "", false},
+		{"two files", []string{"a.go", "b.go"}, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			filename, target, err := ParseArgs(tt.args)
+			if (err == nil) != tt.valid || filename != tt.filename || target != tt.target {
+				t.Errorf("ParseArgs(%q) = %q, %q, %v. Expected %q, %q, valid: %t", tt.args, filename, target, err, tt.filename, tt.target, tt.valid)
+			}
+		})
+	}
+}
+
+func TestOpenAtPosition(t *testing.T) {
+	setConfigDirs(t)
+	file := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(file, []byte("func f() {\n\tx := 1\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Column 4 of the file is the ':' of ':=', after the tab.
+	filename, target, err := ParseArgs([]string{file + ":2:4:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := testEditor()
+	e.wRows, e.wCols = 11, 40
+	if err := e.Open(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Goto(target); err != nil {
+		t.Fatal(err)
+	}
+	if l := e.GetCurrentRow().Render(); e.cy != 1 || e.cx != 6 || l[e.cx] != ':' {
+		t.Errorf("Expected the cursor on ':' at (6, 1), got (%d, %d) in %q", e.cx, e.cy, l)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+// Maximum number of rows searched for a matching bracket.
+const MAX_BRACKET_ROWS = 1000
+
+// Pairs of opening and closing brackets.
+var bracketPairs = map[byte]byte{'(': ')', '[': ']', '{': '}'}
+
+// literalScheme colours only strings and comments, so Tokenize marks which characters are within literals.
+var literalScheme = ColourScheme{Strings: "s", Chars: "s", Comments: "c", Todos: "c", Name: "literals"}
+
+// BracketMatch is a bracket, and its matching partner, as (x, y) coordinates in a file.
+type BracketMatch struct {
+	x, y   uint
+	mx, my uint
+}
+
+// Literals returns whether each character of row x, starting in lexer state in, is within a string or comment.
+func (s *Syntax) Literals(x string, in HlState) []bool {
+	if s.literal == nil {
+		l := s.l
+		l.HlStrings = true // Strings are literals, even if not highlighted.
+		s.literal = &Syntax{l: l, c: literalScheme, cache: *CreateCache[highlightKey, []Colour](HIGHLIGHT_CACHE_SIZE)}
+		s.literal.rules = s.literal.compileRules()
+	}
+
+	hl := s.literal.Colours(x, in)
+	result := make([]bool, len(hl))
+	for i, c := range hl {
+		result[i] = len(c) > 0
+	}
+	return result
+}
+
+// bracketCache of the last result of MatchBracket, valid until the rows are next edited.
+type bracketCache struct {
+	x, y, edits uint
+	m           BracketMatch
+	exists      bool
+}
+
+// MatchBracket finds the bracket matching the bracket at (x, y) in rows. Brackets within strings or comments are
+// ignored. Returns false if (x, y) isn't a bracket, or no match is found within MAX_BRACKET_ROWS. The result is cached
+// until the cursor moves or the rows are edited, as it's found on every redraw.
+func (s *Syntax) MatchBracket(rows []Row, x, y uint) (BracketMatch, bool) {
+	if c := s.bracket; c != nil && c.x == x && c.y == y && c.edits == s.edits {
+		return c.m, c.exists
+	}
+	m, exists := s.matchBracket(rows, x, y)
+	s.bracket = &bracketCache{x: x, y: y, edits: s.edits, m: m, exists: exists}
+	return m, exists
+}
+
+func (s *Syntax) matchBracket(rows []Row, x, y uint) (BracketMatch, bool) {
+	if y >= uint(len(rows)) {
+		return BracketMatch{}, false
+	}
+	l := rows[y].Render()
+	if x >= uint(len(l)) || s.Literals(l, s.StateAt(rows, y))[x] {
+		return BracketMatch{}, false
+	}
+
+	b, dir := l[x], 1
+	partner, isOpen := bracketPairs[b]
+	if !isOpen {
+		for o, c := range bracketPairs {
+			if c == b {
+				partner, dir = o, -1
+			}
+		}
+		if dir == 1 {
+			return BracketMatch{}, false
+		}
+	}
+
+	depth := 0
+	i := int(x)
+	for j := int(y); j >= 0 && j < len(rows) && (j-int(y))*dir < MAX_BRACKET_ROWS; j += dir {
+		l = rows[j].Render()
+		literals := s.Literals(l, s.StateAt(rows, uint(j)))
+		if j != int(y) {
+			i = 0
+			if dir == -1 {
+				i = len(l) - 1
+			}
+		}
+
+		for ; i >= 0 && i < len(l); i += dir {
+			if literals[i] {
+				continue
+			}
+			if l[i] == b {
+				depth++
+			} else if l[i] == partner {
+				depth--
+			}
+			if depth == 0 {
+				return BracketMatch{x: x, y: y, mx: uint(i), my: uint(j)}, true
+			}
+		}
+	}
+	return BracketMatch{}, false
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMatchBracket(t *testing.T) {
+	s := CreateSyntaxFor(LanguageSyntax{
+		Comment:       "//",
+		StringChars:   []string{"\""},
+		CharChars:     []string{"'"},
+		BlockComments: []Delimiters{{Start: "/*", End: "*/"}},
+	})
+	rows := []Row{
+		ConstructRow(`func f(a []int) {`),
+		ConstructRow(`	x := "(" + ')' // )`),
+		ConstructRow(`	/* } */ g(a[0])`),
+		ConstructRow(`}`),
+	}
+
+	type testParam struct {
+		description string
+		x, y        uint
+		expected    BracketMatch
+		exists      bool
+	}
+	tests := []testParam{
+		{"open to close on row", 6, 0, BracketMatch{6, 0, 14, 0}, true},
+		{"close to open on row", 14, 0, BracketMatch{14, 0, 6, 0}, true},
+		{"nested square brackets", 9, 0, BracketMatch{9, 0, 10, 0}, true},
+		{"across rows, skipping strings and comments", 16, 0, BracketMatch{16, 0, 0, 3}, true},
+		{"backwards across rows", 0, 3, BracketMatch{0, 3, 16, 0}, true},
+		{"nested after block comment", 13, 2, BracketMatch{13, 2, 18, 2}, true},
+		{"not a bracket", 0, 0, BracketMatch{}, false},
+		{"bracket in string", 10, 1, BracketMatch{}, false},
+		{"bracket in comment", 22, 1, BracketMatch{}, false},
+		{"past end of row", 40, 0, BracketMatch{}, false},
+		{"past end of file", 0, 10, BracketMatch{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			m, exists := s.MatchBracket(rows, tt.x, tt.y)
+			if exists != tt.exists || m != tt.expected {
+				t.Errorf("MatchBracket(%d, %d) = %+v, %t. Expected %+v, %t", tt.x, tt.y, m, exists, tt.expected, tt.exists)
+			}
+		})
+	}
+}
+
+func TestMatchBracketUnmatched(t *testing.T) {
+	s := CreateSyntaxFor(LanguageSyntax{StringChars: []string{"\""}})
+	rows := []Row{ConstructRow(`f("(", g(`), ConstructRow(`)`)}
+	if m, exists := s.MatchBracket(rows, 1, 0); exists {
+		t.Errorf("Expected no match for unclosed bracket, got %+v", m)
+	}
+	if m, exists := s.MatchBracket(rows, 8, 0); !exists || m.my != 1 {
+		t.Errorf("Expected match on next row, got %+v, %t", m, exists)
+	}
+}
+
+func TestMatchBracketCached(t *testing.T) {
+	s := CreateSyntaxFor(LanguageSyntax{})
+	rows := []Row{ConstructRow(`f(x)`)}
+	if m, exists := s.MatchBracket(rows, 1, 0); !exists || m.mx != 3 {
+		t.Fatalf("Expected match at 3, got %+v, %t", m, exists)
+	}
+
+	rows[0] = ConstructRow(`f(xy)`)
+	if m, _ := s.MatchBracket(rows, 1, 0); m.mx != 3 {
+		t.Errorf("Expected the cached match at 3 until the rows are invalidated, got %+v", m)
+	}
+	s.Invalidate(0)
+	if m, _ := s.MatchBracket(rows, 1, 0); m.mx != 4 {
+		t.Errorf("Expected the match at 4 after an edit, got %+v", m)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// CLIPBOARD_ENV selects a clipboard by name, overriding DetectClipboard.
+const CLIPBOARD_ENV = "GRAM_CLIPBOARD"
+
+// Maximum length of text copied with OSC 52, as many terminals ignore longer sequences.
+const OSC52_MAX_LEN = 100000
+
+// ErrNoPaste is returned by clipboards that can be copied to, but not read from.
+var ErrNoPaste = errors.New("clipboard can't be pasted from")
+
+// Clipboard that copied text is written to, and pasted text read from.
+type Clipboard interface {
+	Name() string
+	Copy(text string) error
+	Paste() (string, error)
+}
+
+// RegisterClipboard is the editor's internal register, only shared within gram.
+type RegisterClipboard struct {
+	text string
+}
+
+func (c *RegisterClipboard) Name() string { return "internal" }
+
+func (c *RegisterClipboard) Copy(text string) error {
+	c.text = text
+	return nil
+}
+
+func (c *RegisterClipboard) Paste() (string, error) {
+	return c.text, nil
+}
+
+// OSC52Clipboard copies by writing an OSC 52 escape sequence to the terminal, which sets the clipboard of the
+// terminal's desktop, including over SSH. Terminals rarely allow reading the clipboard, so it can't be pasted from.
+type OSC52Clipboard struct {
+	w io.Writer
+}
+
+func (c *OSC52Clipboard) Name() string { return "osc52" }
+
+func (c *OSC52Clipboard) Copy(text string) error {
+	if len(text) > OSC52_MAX_LEN {
+		return fmt.Errorf("%d bytes is too large to copy with OSC 52", len(text))
+	}
+	_, err := fmt.Fprintf(c.w, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+func (c *OSC52Clipboard) Paste() (string, error) {
+	return "", ErrNoPaste
+}
+
+// CommandClipboard runs a command to copy, writing text to its stdin, and a command to paste, reading its stdout.
+type CommandClipboard struct {
+	name        string
+	copy, paste []string
+}
+
+func (c *CommandClipboard) Name() string { return c.name }
+
+// Copy by running the copy command. Tools such as xclip and wl-copy fork a process that keeps serving the clipboard,
+// and would hold a pipe open, so stderr is written to a file rather than captured through a pipe.
+func (c *CommandClipboard) Copy(text string) error {
+	stderr, err := os.CreateTemp("", "gram-clipboard")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+
+	cmd := exec.Command(c.copy[0], c.copy[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		out, _ := os.ReadFile(stderr.Name())
+		return fmt.Errorf("%s: %w %s", c.copy[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+func (c *CommandClipboard) Paste() (string, error) {
+	out, err := exec.Command(c.paste[0], c.paste[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", c.paste[0], err)
+	}
+	return string(out), nil
+}
+
+// clipboardCommands of desktop clipboard tools, in order of preference, with the variable that must be set for the
+// tool's display server to be running.
+var clipboardCommands = []struct {
+	display string
+	c       CommandClipboard
+}{
+	{"WAYLAND_DISPLAY", CommandClipboard{"wl-copy", []string{"wl-copy"}, []string{"wl-paste", "--no-newline"}}},
+	{"DISPLAY", CommandClipboard{"xclip", []string{"xclip", "-selection", "clipboard", "-in"}, []string{"xclip", "-selection", "clipboard", "-out"}}},
+	{"DISPLAY", CommandClipboard{"xsel", []string{"xsel", "--clipboard", "--input"}, []string{"xsel", "--clipboard", "--output"}}},
+}
+
+// Clipboards available by name, for CLIPBOARD_ENV. Command clipboards are only available if installed.
+func Clipboards(w io.Writer) map[string]Clipboard {
+	clipboards := map[string]Clipboard{
+		"internal": &RegisterClipboard{},
+		"osc52":    &OSC52Clipboard{w: w},
+	}
+	for _, cmd := range clipboardCommands {
+		_, errCopy := exec.LookPath(cmd.c.copy[0])
+		_, errPaste := exec.LookPath(cmd.c.paste[0])
+		if errCopy == nil && errPaste == nil {
+			c := cmd.c
+			clipboards[c.name] = &c
+		}
+	}
+	return clipboards
+}
+
+// DetectClipboard to copy to: the clipboard named by CLIPBOARD_ENV, else the first installed tool of clipboardCommands
+// whose display server is running, else OSC 52, written to w.
+func DetectClipboard(w io.Writer) (Clipboard, error) {
+	clipboards := Clipboards(w)
+	if name := os.Getenv(CLIPBOARD_ENV); len(name) > 0 {
+		c, exists := clipboards[name]
+		if !exists {
+			return clipboards["osc52"], fmt.Errorf("%s: clipboard %q is unknown or not installed", CLIPBOARD_ENV, name)
+		}
+		return c, nil
+	}
+
+	for _, cmd := range clipboardCommands {
+		if c, exists := clipboards[cmd.c.name]; exists && len(os.Getenv(cmd.display)) > 0 {
+			return c, nil
+		}
+	}
+	return clipboards["osc52"], nil
+}
+
+// SetClipboard to text, in the internal register and the editor's clipboard.
+func (e *Editor) SetClipboard(text string) {
+	e.paste = text
+	if e.clipboard == nil {
+		return
+	}
+	if err := e.clipboard.Copy(text); err != nil {
+		e.SetStatusMessage("Copied to gram only. Clipboard %s: %s", e.clipboard.Name(), err)
+	}
+}
+
+// ClipboardText to paste, from the editor's clipboard, or the internal register if the clipboard can't be read.
+func (e *Editor) ClipboardText() string {
+	if e.clipboard == nil {
+		return e.paste
+	}
+	text, err := e.clipboard.Paste()
+	if err != nil {
+		if !errors.Is(err, ErrNoPaste) {
+			e.SetStatusMessage("Pasted from gram. Clipboard %s: %s", e.clipboard.Name(), err)
+		}
+		return e.paste
+	}
+	return cleanPaste([]byte(text))
+}
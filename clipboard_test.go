@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOSC52Clipboard(t *testing.T) {
+	var b bytes.Buffer
+	c := &OSC52Clipboard{w: &b}
+	if err := c.Copy("hi\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); s != "\x1b]52;c;aGkK\a" {
+		t.Errorf("Copy() wrote %q", s)
+	}
+	if _, err := c.Paste(); err != ErrNoPaste {
+		t.Errorf("Paste() = %v, expected ErrNoPaste", err)
+	}
+
+	b.Reset()
+	if err := c.Copy(strings.Repeat("x", OSC52_MAX_LEN+1)); err == nil || b.Len() > 0 {
+		t.Errorf("Expected copying more than OSC52_MAX_LEN to fail without writing")
+	}
+}
+
+func TestCommandClipboard(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "clipboard")
+	c := &CommandClipboard{"test", []string{"sh", "-c", "cat > " + file}, []string{"cat", file}}
+	if err := c.Copy("a\n\tb"); err != nil {
+		t.Fatal(err)
+	}
+	if text, err := c.Paste(); err != nil || text != "a\n\tb" {
+		t.Errorf("Paste() = %q, %v", text, err)
+	}
+
+	failing := &CommandClipboard{"test", []string{"sh", "-c", "echo denied >&2; false"}, []string{"false"}}
+	if err := failing.Copy("a"); err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("Expected Copy() to fail with the command's error, got %v", err)
+	}
+	if _, err := failing.Paste(); err == nil {
+		t.Errorf("Expected Paste() to fail")
+	}
+}
+
+func TestCommandClipboardBackground(t *testing.T) {
+	// Like xclip, the copy command forks a process that outlives it, holding its stdout and stderr.
+	c := &CommandClipboard{"test", []string{"sh", "-c", "cat > /dev/null; sleep 3 &"}, []string{"true"}}
+	start := time.Now()
+	if err := c.Copy("a"); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Copy() waited %s for the background process", d)
+	}
+}
+
+func TestDetectClipboard(t *testing.T) {
+	bin := t.TempDir()
+	for _, tool := range []string{"xclip", "wl-copy"} { // wl-paste is missing
+		if err := os.WriteFile(filepath.Join(bin, tool), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin)
+
+	type testParam struct {
+		description string
+		env         string
+		display     string
+		wayland     string
+		expected    string
+		valid       bool
+	}
+	tests := []testParam{
+		{"x11 tool", "", ":0", "", "xclip", true},
+		{"wayland tool not installed", "", "", "wayland-0", "osc52", true},
+		{"no display", "", "", "", "osc52", true},
+		{"selected", "internal", ":0", "", "internal", true},
+		{"selected not installed", "xsel", ":0", "", "osc52", false},
+		{"selected unknown", "pbcopy", "", "", "osc52", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			t.Setenv(CLIPBOARD_ENV, tt.env)
+			t.Setenv("DISPLAY", tt.display)
+			t.Setenv("WAYLAND_DISPLAY", tt.wayland)
+			c, err := DetectClipboard(&bytes.Buffer{})
+			if c.Name() != tt.expected || (err == nil) != tt.valid {
+				t.Errorf("DetectClipboard() = %s, %v. Expected %s, valid: %t", c.Name(), err, tt.expected, tt.valid)
+			}
+		})
+	}
+}
+
+func TestEditorClipboard(t *testing.T) {
+	var b bytes.Buffer
+	e := testEditor()
+	e.clipboard = &OSC52Clipboard{w: &b}
+	e.SetClipboard("copied")
+	if e.paste != "copied" || b.Len() == 0 {
+		t.Errorf("Expected copy to the register and terminal, got %q and %q", e.paste, b.String())
+	}
+	if text := e.ClipboardText(); text != "copied" {
+		t.Errorf("ClipboardText() = %q, expected the register", text)
+	}
+
+	file := filepath.Join(t.TempDir(), "clipboard")
+	os.WriteFile(file, []byte("from\r\ndesktop"), 0644)
+	e.clipboard = &CommandClipboard{"test", []string{"false"}, []string{"cat", file}}
+	e.SetClipboard("again")
+	if e.paste != "again" || len(e.statusMsg) == 0 {
+		t.Errorf("Expected copy to the register with an error when the clipboard fails, got %q", e.paste)
+	}
+	if text := e.ClipboardText(); text != "from\ndesktop" {
+		t.Errorf("ClipboardText() = %q, expected the clipboard", text)
+	}
+}
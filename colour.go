@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
 const (
@@ -25,47 +26,259 @@ const (
 	LightGray          = "\033[37m"
 	DarkGray           = "\033[90m"
 	White              = "\033[97m"
+	Reverse            = "\033[7m"
 )
 
+// ColourScheme of escape sequences for each highlighted slot.
 type ColourScheme struct {
-	Keyword  Colour `json:"Keyword"`
-	Strings  Colour `json:"Strings"`
-	Comments Colour `json:"Comments"`
-	Numbers  Colour `json:"Numbers"`
-	Todos    Colour `json:"Todos"`
-	Name     string `json:"Name"`
+	Keyword    Colour
+	Types      Colour
+	Builtins   Colour
+	Constants  Colour
+	Functions  Colour
+	Decorators Colour
+	Strings    Colour
+	Chars      Colour
+	Comments   Colour
+	Numbers    Colour
+	Todos      Colour
+
+	// Editor chrome
+	StatusBar   Colour
+	Message     Colour // Status messages, i.e. errors, replacing the status bar
+	LineNumbers Colour
+	CurrentLine Colour // Background of the row with the cursor
+	Selection   Colour
+	SearchMatch Colour
+	Tildes      Colour // Markers of rows after the end of the file
+	Brackets    Colour // The bracket at the cursor, and its match
+
+	Name string
+}
+
+// ColourSchemeSpec is a colour scheme as defined in colours.json: a Name, and a Style for any slots that differ from
+// the default colour scheme. i.e. {"Name": "Mine", "Keyword": {"fg": "#ff8700", "bold": true}, "Strings": "Green"}
+type ColourSchemeSpec struct {
+	Name  string
+	Slots map[string]Style
+}
+
+func (c *ColourSchemeSpec) UnmarshalJSON(b []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+
+	c.Slots = make(map[string]Style)
+	for k, v := range fields {
+		if k == "Name" {
+			if err := json.Unmarshal(v, &c.Name); err != nil {
+				return err
+			}
+			continue
+		}
+		var s Style
+		if err := json.Unmarshal(v, &s); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		c.Slots[k] = s
+	}
+	return nil
+}
+
+// Resolve the spec into escape sequences for a terminal of the given colour depth.
+func (c ColourSchemeSpec) Resolve(depth ColourDepth) (ColourScheme, error) {
+	defaults := DefaultColourScheme(depth)
+	scheme := defaults
+	scheme.Name = c.Name
+	for slot, style := range c.Slots {
+		colour, err := style.Escape(depth)
+		if err != nil {
+			return defaults, fmt.Errorf("colour scheme %s, %s: %w", c.Name, slot, err)
+		}
+		if !scheme.SetSlot(slot, colour) {
+			return defaults, fmt.Errorf("colour scheme %s: unknown slot %s", c.Name, slot)
+		}
+	}
+	return scheme, nil
+}
+
+// defaultStyles of each slot of the default colour scheme.
+var defaultStyles = map[string]Style{
+	"Keyword": {Fg: "Orange"}, "Types": {Fg: "Cyan"}, "Builtins": {Fg: "DarkCyan"}, "Constants": {Fg: "Magenta"},
+	"Functions": {Fg: "White"}, "Decorators": {Fg: "DarkMagenta"}, "Strings": {Fg: "Green"}, "Chars": {Fg: "DarkGreen"},
+	"Comments": {Fg: "DarkGray"}, "Numbers": {Fg: "Blue"}, "Todos": {Fg: "DarkYellow"},
+	"StatusBar": {Fg: "DarkBlue"}, "Message": {Fg: "Red"}, "LineNumbers": {Fg: "DarkGray"}, "CurrentLine": {Bg: "236"},
+	"Selection": {Reverse: true}, "SearchMatch": {Fg: "Black", Bg: "DarkYellow"}, "Tildes": {Fg: "Blue"},
+	"Brackets": {Bold: true, Underline: true},
 }
 
-var defaultColourScheme = ColourScheme{
-	Keyword: Orange, Strings: Green, Comments: DarkGray, Numbers: Blue, Todos: DarkYellow, Name: "Default",
+// DefaultColourScheme of defaultStyles, for a terminal of the given colour depth.
+func DefaultColourScheme(depth ColourDepth) ColourScheme {
+	scheme := ColourScheme{Name: "Default"}
+	for slot, style := range defaultStyles {
+		colour, _ := style.Escape(depth) // Valid, as tested
+		scheme.SetSlot(slot, colour)
+	}
+	return scheme
+}
+
+// Slot returns the colour of a named slot in the colour scheme (i.e. "Keyword", "Functions").
+func (c ColourScheme) Slot(name string) (Colour, bool) {
+	switch name {
+	case "Keyword":
+		return c.Keyword, true
+	case "Types":
+		return c.Types, true
+	case "Builtins":
+		return c.Builtins, true
+	case "Constants":
+		return c.Constants, true
+	case "Functions":
+		return c.Functions, true
+	case "Decorators":
+		return c.Decorators, true
+	case "Strings":
+		return c.Strings, true
+	case "Chars":
+		return c.Chars, true
+	case "Comments":
+		return c.Comments, true
+	case "Numbers":
+		return c.Numbers, true
+	case "Todos":
+		return c.Todos, true
+	case "StatusBar":
+		return c.StatusBar, true
+	case "Message":
+		return c.Message, true
+	case "LineNumbers":
+		return c.LineNumbers, true
+	case "CurrentLine":
+		return c.CurrentLine, true
+	case "Selection":
+		return c.Selection, true
+	case "SearchMatch":
+		return c.SearchMatch, true
+	case "Tildes":
+		return c.Tildes, true
+	case "Brackets":
+		return c.Brackets, true
+	}
+	return "", false
 }
 
+// SetSlot of the colour scheme by name. Returns false if no slot has the name.
+func (c *ColourScheme) SetSlot(name string, colour Colour) bool {
+	switch name {
+	case "Keyword":
+		c.Keyword = colour
+	case "Types":
+		c.Types = colour
+	case "Builtins":
+		c.Builtins = colour
+	case "Constants":
+		c.Constants = colour
+	case "Functions":
+		c.Functions = colour
+	case "Decorators":
+		c.Decorators = colour
+	case "Strings":
+		c.Strings = colour
+	case "Chars":
+		c.Chars = colour
+	case "Comments":
+		c.Comments = colour
+	case "Numbers":
+		c.Numbers = colour
+	case "Todos":
+		c.Todos = colour
+	case "StatusBar":
+		c.StatusBar = colour
+	case "Message":
+		c.Message = colour
+	case "LineNumbers":
+		c.LineNumbers = colour
+	case "CurrentLine":
+		c.CurrentLine = colour
+	case "Selection":
+		c.Selection = colour
+	case "SearchMatch":
+		c.SearchMatch = colour
+	case "Tildes":
+		c.Tildes = colour
+	case "Brackets":
+		c.Brackets = colour
+	default:
+		return false
+	}
+	return true
+}
+
+// GetColourScheme named by $GRAM_COLOUR_SCHEME, for the terminal's colour depth. Falls back to the default scheme if
+// unset, not found or invalid.
 func GetColourScheme() ColourScheme {
+	depth := DetectColourDepth()
 	v, exists := os.LookupEnv("GRAM_COLOUR_SCHEME")
 	if !exists {
-		return defaultColourScheme
+		return DefaultColourScheme(depth)
 	}
-	colours := LoadColourSchemesFromFile("colours.json")
+	colours, _ := LoadColourSchemes()
 	for _, c := range colours {
 		if c.Name == v {
-			// TODO: Refactor hor colours are stored to allow for better colour scheme formats
-			// TODO: Also, can't natively store \033 in file. More reason to migrate colour format
-			return defaultColourScheme
+			scheme, err := c.Resolve(depth)
+			if err != nil {
+				return DefaultColourScheme(depth)
+			}
+			return scheme
 		}
 	}
-	return defaultColourScheme
+	return DefaultColourScheme(depth)
 }
 
-func LoadColourSchemesFromFile(file string) []ColourScheme {
+// LoadColourSchemes from each of ConfigDirs. Schemes in higher precedence directories replace those of the same name.
+// Errors from malformed files are returned alongside the schemes that did load.
+func LoadColourSchemes() ([]ColourSchemeSpec, []error) {
+	schemes := make([]ColourSchemeSpec, 0)
+	errs := make([]error, 0)
+
+	dirs := ConfigDirs()
+	for i := len(dirs) - 1; i >= 0; i-- {
+		overrides, err := LoadColourSchemesFromFile(filepath.Join(dirs[i], COLOURS_FILE))
+		if isConfigError(err) {
+			errs = append(errs, err)
+		}
+		for _, o := range overrides {
+			replaced := false
+			for j := range schemes {
+				if schemes[j].Name == o.Name {
+					schemes[j], replaced = o, true
+				}
+			}
+			if !replaced {
+				schemes = append(schemes, o)
+			}
+		}
+	}
+	return schemes, errs
+}
+
+// LoadColourSchemesFromFile, validating each scheme resolves to escape sequences.
+func LoadColourSchemesFromFile(file string) ([]ColourSchemeSpec, error) {
 	bytes, err := os.ReadFile(file)
 	if err != nil {
-		return []ColourScheme{}
+		return []ColourSchemeSpec{}, err
 	}
 
-	var colours []ColourScheme
+	var colours []ColourSchemeSpec
 	err = json.Unmarshal(bytes, &colours)
 	if err != nil {
-		panic(fmt.Errorf("%w", err))
+		return []ColourSchemeSpec{}, fmt.Errorf("%s: %w", file, err)
+	}
+	for _, c := range colours {
+		if _, err := c.Resolve(COLOURS_TRUE); err != nil {
+			return []ColourSchemeSpec{}, fmt.Errorf("%s: %w", file, err)
+		}
 	}
-	return colours
+	return colours, nil
 }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStyleEscape(t *testing.T) {
+	type testParam struct {
+		description string
+		style       Style
+		depth       ColourDepth
+		expected    Colour
+	}
+	tests := []testParam{
+		{"empty", Style{}, COLOURS_TRUE, ""},
+		{"named basic colour", Style{Fg: "Green"}, COLOURS_16, "\033[92m"},
+		{"named dark colour", Style{Fg: "DarkGreen"}, COLOURS_TRUE, "\033[32m"},
+		{"named 256 colour", Style{Fg: "Orange"}, COLOURS_256, "\033[38;5;202m"},
+		{"named 256 colour degraded", Style{Fg: "Orange"}, COLOURS_16, "\033[91m"},
+		{"palette index", Style{Fg: "244"}, COLOURS_256, "\033[38;5;244m"},
+		{"hex truecolor", Style{Fg: "#ff8700"}, COLOURS_TRUE, "\033[38;2;255;135;0m"},
+		{"hex to 256", Style{Fg: "#ff8700"}, COLOURS_256, "\033[38;5;208m"},
+		{"hex to 16", Style{Fg: "#0000f0"}, COLOURS_16, "\033[34m"},
+		{"background", Style{Bg: "#303030"}, COLOURS_256, "\033[48;5;236m"},
+		{"default background", Style{Fg: "Red", Bg: "Default"}, COLOURS_16, "\033[91;49m"},
+		{"attributes", Style{Fg: "Red", Bold: true, Italic: true, Underline: true}, COLOURS_16, "\033[1;3;4;91m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c, err := tt.style.Escape(tt.depth)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if c != tt.expected {
+				t.Errorf("Escape(%d) = %q, expected %q", tt.depth, c, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStyleEscapeInvalid(t *testing.T) {
+	for _, c := range []string{"Purple", "256", "-1", "#fff", "#gggggg"} {
+		if _, err := (Style{Fg: c}).Escape(COLOURS_TRUE); err == nil {
+			t.Errorf("Expected error for colour %q", c)
+		}
+	}
+}
+
+func TestDetectColourDepth(t *testing.T) {
+	type testParam struct {
+		description string
+		colorterm   string
+		term        string
+		expected    ColourDepth
+	}
+	tests := []testParam{
+		{"truecolor", "truecolor", "xterm", COLOURS_TRUE},
+		{"24bit", "24bit", "xterm-256color", COLOURS_TRUE},
+		{"256 colour", "", "xterm-256color", COLOURS_256},
+		{"basic", "", "xterm", COLOURS_16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			t.Setenv("COLORTERM", tt.colorterm)
+			t.Setenv("TERM", tt.term)
+			if d := DetectColourDepth(); d != tt.expected {
+				t.Errorf("DetectColourDepth() = %d, expected %d", d, tt.expected)
+			}
+		})
+	}
+}
+
+func TestColourSchemeSpec(t *testing.T) {
+	var specs []ColourSchemeSpec
+	err := json.Unmarshal([]byte(`[{"Name": "x", "Strings": "Green", "Keyword": {"fg": "#ff0000", "bold": true}}]`), &specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := specs[0].Resolve(COLOURS_TRUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "x" || c.Strings != "\033[92m" || c.Keyword != "\033[1;38;2;255;0;0m" {
+		t.Errorf("Unexpected colour scheme %q", c)
+	}
+	if c.Comments != DefaultColourScheme(COLOURS_TRUE).Comments {
+		t.Errorf("Expected unset slots to be the default, got %q", c.Comments)
+	}
+
+	if _, err = (ColourSchemeSpec{Name: "y", Slots: map[string]Style{"Nope": {Fg: "Red"}}}).Resolve(COLOURS_16); err == nil {
+		t.Errorf("Expected error for unknown slot")
+	}
+}
+
+func TestGetColourScheme(t *testing.T) {
+	user, _, _ := setConfigDirs(t)
+	writeConfigFile(t, user, COLOURS_FILE, `[{"Name": "mine", "Numbers": "#00ff00"}]`)
+	t.Setenv("COLORTERM", "")
+	t.Setenv("TERM", "xterm-256color")
+
+	t.Setenv("GRAM_COLOUR_SCHEME", "mine")
+	if c := GetColourScheme(); c.Name != "mine" || c.Numbers != "\033[38;5;46m" {
+		t.Errorf("Expected configured colour scheme, got %q", c)
+	}
+
+	t.Setenv("GRAM_COLOUR_SCHEME", "missing")
+	if c := GetColourScheme(); c != DefaultColourScheme(COLOURS_256) {
+		t.Errorf("Expected default colour scheme, got %q", c)
+	}
+}
+
+func TestDefaultColourScheme(t *testing.T) {
+	type testParam struct {
+		depth       ColourDepth
+		keyword     Colour
+		currentLine Colour
+	}
+	tests := []testParam{
+		{COLOURS_16, "\033[91m", "\033[40m"},
+		{COLOURS_256, "\033[38;5;202m", "\033[48;5;236m"},
+		{COLOURS_TRUE, "\033[38;5;202m", "\033[48;5;236m"},
+	}
+	for _, tt := range tests {
+		c := DefaultColourScheme(tt.depth)
+		if c.Keyword != tt.keyword || c.CurrentLine != tt.currentLine {
+			t.Errorf("DefaultColourScheme(%d) Keyword %q, CurrentLine %q. Expected %q, %q", tt.depth, c.Keyword, c.CurrentLine, tt.keyword, tt.currentLine)
+		}
+		for slot := range defaultStyles {
+			if colour, _ := c.Slot(slot); len(colour) == 0 {
+				t.Errorf("DefaultColourScheme(%d) has no %s", tt.depth, slot)
+			}
+			if colour, _ := c.Slot(slot); tt.depth == COLOURS_16 && strings.Contains(string(colour), ";5;") {
+				t.Errorf("DefaultColourScheme(16) %s is a 256 colour %q", slot, colour)
+			}
+		}
+	}
+}
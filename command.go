@@ -0,0 +1,314 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// errUsage is returned by commands run with the wrong arguments, and reported with the command's usage.
+var errUsage = errors.New("usage")
+
+// exCommand that can be run from the command prompt, i.e. ":w file".
+type exCommand struct {
+	name     string
+	args     string                                       // Usage of the arguments, i.e. "FILE"
+	complete func(args []string) []string                 // Completions of the last argument, or nil
+	run      func(e *Editor, args []string) (bool, error) // Returns true if the editor should exit.
+}
+
+// exCommands that can be run from the command prompt, in the order they're completed.
+func exCommands() []exCommand {
+	return []exCommand{
+		{"w", "[FILE]", completeFile, func(e *Editor, args []string) (bool, error) {
+			return false, e.runWrite(args)
+		}},
+		{"q", "", nil, func(e *Editor, args []string) (bool, error) {
+			if e.dirty {
+				return false, errUnsaved("q")
+			}
+			return true, nil
+		}},
+		{"q!", "", nil, func(e *Editor, args []string) (bool, error) {
+			return true, nil
+		}},
+		{"wq", "[FILE]", completeFile, func(e *Editor, args []string) (bool, error) {
+			err := e.runWrite(args)
+			return err == nil, err
+		}},
+		{"e", "FILE", completeFile, func(e *Editor, args []string) (bool, error) {
+			if len(args) != 1 {
+				return false, errUsage
+			}
+			err := e.Open(args[0])
+			if errors.Is(err, ErrUnsaved) {
+				return false, errUnsaved("e")
+			}
+			return false, err
+		}},
+		{"e!", "FILE", completeFile, func(e *Editor, args []string) (bool, error) {
+			if len(args) != 1 {
+				return false, errUsage
+			}
+			return false, e.Reopen(args[0])
+		}},
+		{"goto", "LINE[:COL]", nil, func(e *Editor, args []string) (bool, error) {
+			if len(args) != 1 {
+				return false, errUsage
+			}
+			return false, e.Goto(args[0])
+		}},
+		{"set", "OPTION [VALUE]", completeOption, func(e *Editor, args []string) (bool, error) {
+			return false, e.runSet(args)
+		}},
+	}
+}
+
+// errUnsaved refusing to run command name over unsaved changes, suggesting its form that discards them.
+func errUnsaved(name string) error {
+	return fmt.Errorf("%w (use %s! to discard them)", ErrUnsaved, name)
+}
+
+// findCommand by name.
+func findCommand(name string) (exCommand, bool) {
+	for _, c := range exCommands() {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return exCommand{}, false
+}
+
+// editorOption that can be set with ":set".
+type editorOption struct {
+	name   string
+	values func() []string // Completions of the option's value
+	set    func(e *Editor, value string) error
+}
+
+// editorOptions that can be set with ":set", in the order they're completed.
+func editorOptions() []editorOption {
+	return []editorOption{
+		{"number", func() []string { return []string{"on", "off"} }, func(e *Editor, value string) error {
+			on, err := parseSwitch(value)
+			if err != nil {
+				return err
+			}
+			e.lineNumbers = on
+			return nil
+		}},
+		{"language", languageNames, func(e *Editor, value string) error {
+			l, exists := GetLanguageSyntaxByName(value)
+			if !exists {
+				return fmt.Errorf("unknown language %q", value)
+			}
+			e.syntax = CreateSyntaxFor(l)
+			return nil
+		}},
+		{"clipboard", clipboardNames, func(e *Editor, value string) error {
+			c, exists := Clipboards(os.Stdout)[value]
+			if !exists {
+				return fmt.Errorf("clipboard %q is unknown or not installed", value)
+			}
+			e.clipboard = c
+			return nil
+		}},
+	}
+}
+
+// parseSwitch value of an on/off option. An empty value is on.
+func parseSwitch(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "", "on", "true", "yes", "1":
+		return true, nil
+	case "off", "false", "no", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected on or off, not %q", value)
+}
+
+func languageNames() []string {
+	syntaxes, _ := LoadLanguageSyntaxes()
+	names := []string{defaultSyntax.Name}
+	for _, l := range syntaxes {
+		names = append(names, strings.ToLower(l.Name))
+	}
+	return names
+}
+
+func clipboardNames() []string {
+	names := make([]string, 0)
+	for name := range Clipboards(os.Stdout) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunCommand prompts for a command, and runs it. Returns true if the editor should exit.
+func (e *Editor) RunCommand() bool {
+	q, ok := e.PromptWithCompletion(":", e.commandHistory, CompleteCommand)
+	if !ok || len(strings.TrimSpace(q)) == 0 {
+		return false
+	}
+	exit, err := e.ExecCommand(q)
+	if err != nil {
+		e.SetStatusMessage("Command error: %s", err)
+	}
+	return exit
+}
+
+// ExecCommand q, of a command name and its space separated arguments. Returns true if the editor should exit.
+func (e *Editor) ExecCommand(q string) (bool, error) {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(q), ":"))
+	if len(fields) == 0 {
+		return false, nil
+	}
+	c, exists := findCommand(fields[0])
+	if !exists {
+		return false, fmt.Errorf("%s: unknown command", fields[0])
+	}
+	exit, err := c.run(e, fields[1:])
+	if errors.Is(err, errUsage) {
+		return false, fmt.Errorf("usage: %s %s", c.name, c.args)
+	}
+	return exit, err
+}
+
+// CompleteCommand input q of the command prompt, to the inputs it could be completed to. The command name is
+// completed, then its last argument. Completed command and option names end in a space, ready for their arguments.
+func CompleteCommand(q string) []string {
+	fields := strings.Fields(q)
+	if len(q) > 0 && q[len(q)-1] == ' ' {
+		fields = append(fields, "") // Completing a new argument
+	}
+
+	if len(fields) <= 1 {
+		prefix := strings.Join(fields, "")
+		names := make([]string, 0)
+		for _, c := range exCommands() {
+			if strings.HasPrefix(c.name, prefix) {
+				names = append(names, c.name+" ")
+			}
+		}
+		return names
+	}
+
+	c, exists := findCommand(fields[0])
+	if !exists || c.complete == nil {
+		return []string{}
+	}
+	start := strings.Join(fields[:len(fields)-1], " ") + " "
+	completions := make([]string, 0)
+	for _, s := range c.complete(fields[1:]) {
+		completions = append(completions, start+s)
+	}
+	return completions
+}
+
+// completeFile of the last argument, as a path. Directories end in a separator.
+func completeFile(args []string) []string {
+	if len(args) != 1 {
+		return []string{}
+	}
+	matches, _ := filepath.Glob(escapeGlob(args[0]) + "*")
+	for i, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			matches[i] = m + string(filepath.Separator)
+		}
+	}
+	return matches
+}
+
+// escapeGlob metacharacters in path, so that it matches itself.
+func escapeGlob(path string) string {
+	r := strings.NewReplacer("\\", "\\\\", "*", "\\*", "?", "\\?", "[", "\\[")
+	return r.Replace(path)
+}
+
+// completeOption names, then their values.
+func completeOption(args []string) []string {
+	completions := make([]string, 0)
+	switch len(args) {
+	case 1:
+		for _, o := range editorOptions() {
+			if strings.HasPrefix(o.name, args[0]) {
+				completions = append(completions, o.name+" ")
+			}
+		}
+	case 2:
+		for _, o := range editorOptions() {
+			if o.name != args[0] {
+				continue
+			}
+			for _, v := range o.values() {
+				if strings.HasPrefix(v, args[1]) {
+					completions = append(completions, v)
+				}
+			}
+		}
+	}
+	return completions
+}
+
+// runWrite saves the file, as args[0] if given.
+func (e *Editor) runWrite(args []string) error {
+	if len(args) > 1 {
+		return errUsage
+	}
+	filename := e.filename
+	if len(args) == 1 {
+		filename = args[0]
+	}
+	if err := e.SaveAs(filename); err != nil {
+		return err
+	}
+	e.SetStatusMessage("Wrote %s", e.filename)
+	return nil
+}
+
+// runSet of an option, from args of its name and value.
+func (e *Editor) runSet(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errUsage
+	}
+	value := ""
+	if len(args) == 2 {
+		value = args[1]
+	}
+	for _, o := range editorOptions() {
+		if o.name == args[0] {
+			if err := o.set(e, value); err != nil {
+				return fmt.Errorf("set %s: %w", o.name, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("set: unknown option %q", args[0])
+}
+
+// completionHint listing candidates by their last word.
+func completionHint(candidates []string) string {
+	words := make([]string, len(candidates))
+	for i, c := range candidates {
+		words[i] = c[strings.LastIndex(strings.TrimSuffix(c, " "), " ")+1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// commonPrefix of strs, or "" if there are none.
+func commonPrefix(strs []string) string {
+	if len(strs) == 0 {
+		return ""
+	}
+	prefix := strs[0]
+	for _, s := range strs[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
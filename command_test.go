@@ -0,0 +1,207 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExecCommand(t *testing.T) {
+	setConfigDirs(t)
+	dir := t.TempDir()
+	type testParam struct {
+		description string
+		q           string
+		exit        bool
+		valid       bool
+	}
+	tests := []testParam{
+		{"empty", "", false, true},
+		{"quit", "q", true, true},
+		{"leading colon", ":q", true, true},
+		{"write as", "w " + filepath.Join(dir, "a.txt"), false, true},
+		{"write and quit", "wq " + filepath.Join(dir, "b.txt"), true, true},
+		{"write to missing directory", "wq " + filepath.Join(dir, "missing", "c.txt"), false, false},
+		{"write too many files", "w a b", false, false},
+		{"goto", "goto 2", false, true},
+		{"goto without a line", "goto", false, false},
+		{"goto invalid line", "goto x", false, false},
+		{"set number", "set number off", false, true},
+		{"set number invalid", "set number maybe", false, false},
+		{"set unknown option", "set colour red", false, false},
+		{"set language", "set language go", false, true},
+		{"set unknown language", "set language cobol2", false, false},
+		{"edit without a file", "e", false, false},
+		{"unknown command", "x", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			e := testEditor("one", "two")
+			exit, err := e.ExecCommand(tt.q)
+			if exit != tt.exit || (err == nil) != tt.valid {
+				t.Errorf("ExecCommand(%q) = %t, %v. Expected %t, valid: %t", tt.q, exit, err, tt.exit, tt.valid)
+			}
+		})
+	}
+}
+
+func TestExecCommandEffects(t *testing.T) {
+	setConfigDirs(t)
+	file := filepath.Join(t.TempDir(), "out.txt")
+	e := testEditor("one", "two", "three")
+	e.lineNumbers = true
+
+	for _, q := range []string{"set number off", "goto 5", "w " + file} {
+		if _, err := e.ExecCommand(q); err != nil {
+			t.Fatalf("ExecCommand(%q) = %v", q, err)
+		}
+	}
+	if e.lineNumbers || e.cy != 2 {
+		t.Errorf("Expected line numbers off and the cursor on the last row, got %t and row %d", e.lineNumbers, e.cy)
+	}
+	if b, err := os.ReadFile(file); err != nil || string(b) != "one\ntwo\nthree" || e.filename != file {
+		t.Errorf("w wrote %q, %v as %q", b, err, e.filename)
+	}
+
+	if _, err := e.ExecCommand("e " + file); err != nil || e.cy != 0 || len(e.rows) != 3 {
+		t.Errorf("e %s = %v, cursor row %d", file, err, e.cy)
+	}
+	if _, err := e.ExecCommand("goto"); err == nil || err.Error() != "usage: goto LINE[:COL]" {
+		t.Errorf("Expected the usage of goto, got %v", err)
+	}
+}
+
+func TestExecCommandUnsaved(t *testing.T) {
+	setConfigDirs(t)
+	dir := t.TempDir()
+	file, other := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.go")
+	os.WriteFile(other, []byte("package main"), 0644)
+
+	type testParam struct {
+		description string
+		q           string
+		exit        bool
+		err         string
+		row         string
+	}
+	tests := []testParam{
+		{"quit", "q", false, "unsaved changes (use q! to discard them)", "edited"},
+		{"force quit", "q!", true, "", "edited"},
+		{"edit", "e " + other, false, "unsaved changes (use e! to discard them)", "edited"},
+		{"force edit", "e! " + other, false, "", "package main"},
+		{"write and quit", "wq", true, "", "edited"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			e := testEditor("edited")
+			e.filename = file
+			e.Edited(0)
+			exit, err := e.ExecCommand(tt.q)
+			if exit != tt.exit || (err == nil && tt.err != "") || (err != nil && err.Error() != tt.err) {
+				t.Errorf("ExecCommand(%q) = %t, %v. Expected %t, %q", tt.q, exit, err, tt.exit, tt.err)
+			}
+			if row := string(e.rows[0].src); row != tt.row {
+				t.Errorf("Expected row %q, got %q", tt.row, row)
+			}
+		})
+	}
+}
+
+func TestForceEditThenQuit(t *testing.T) {
+	setConfigDirs(t)
+	dir := t.TempDir()
+	other := filepath.Join(dir, "b.go")
+	os.WriteFile(other, []byte("package main"), 0644)
+
+	e := testEditor("edited")
+	e.filename = filepath.Join(dir, "a.txt")
+	e.Edited(0)
+	e.ax, e.selecting, e.marking, e.mouseDown = 3, true, true, true
+	e.yank = &yankState{}
+	if _, err := e.ExecCommand("e! " + other); err != nil {
+		t.Fatalf("ExecCommand(e!) = %v", err)
+	}
+	if e.selecting || e.marking || e.ax != 0 || e.mouseDown || e.yank != nil {
+		t.Errorf("Expected e! to clear the old document's selection, yank and mouse state")
+	}
+	if exit, err := e.ExecCommand("q"); !exit || err != nil {
+		t.Errorf("ExecCommand(q) after e! = %t, %v. Expected to exit, as the edits were discarded", exit, err)
+	}
+}
+
+func TestWriteAs(t *testing.T) {
+	setConfigDirs(t)
+	dir := t.TempDir()
+	e := testEditor("package main")
+	e.filename = filepath.Join(dir, "a.txt")
+	e.Edited(0)
+
+	if _, err := e.ExecCommand("w " + filepath.Join(dir, "missing", "b.go")); err == nil {
+		t.Fatalf("Expected writing to a missing directory to fail")
+	}
+	if e.filename != filepath.Join(dir, "a.txt") || !e.dirty {
+		t.Errorf("Failed write changed the filename to %q, dirty: %t", e.filename, e.dirty)
+	}
+
+	if _, err := e.ExecCommand("w " + filepath.Join(dir, "b.go")); err != nil {
+		t.Fatalf("ExecCommand(w) = %v", err)
+	}
+	if e.filename != filepath.Join(dir, "b.go") || e.dirty || e.syntax.Name() != "go" {
+		t.Errorf("Expected b.go, saved and highlighted as go, got %q, dirty: %t, syntax: %q", e.filename, e.dirty, e.syntax.Name())
+	}
+}
+
+func TestCompleteCommand(t *testing.T) {
+	setConfigDirs(t)
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "file.go"), []byte{}, 0644)
+
+	type testParam struct {
+		description string
+		q           string
+		expected    []string
+	}
+	tests := []testParam{
+		{"all commands", "", []string{"w ", "q ", "q! ", "wq ", "e ", "e! ", "goto ", "set "}},
+		{"command and its discarding form", "e", []string{"e ", "e! "}},
+		{"command prefix", "w", []string{"w ", "wq "}},
+		{"unique command", "go", []string{"goto "}},
+		{"unknown command", "x ", []string{}},
+		{"command without completion", "goto ", []string{}},
+		{"option", "set n", []string{"set number "}},
+		{"option value", "set number o", []string{"set number on", "set number off"}},
+		{"files", "e " + dir + "/", []string{"e " + dir + "/file.go", "e " + dir + "/sub/"}},
+		{"file prefix", "w " + dir + "/f", []string{"w " + dir + "/file.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			if c := CompleteCommand(tt.q); !reflect.DeepEqual(c, tt.expected) {
+				t.Errorf("CompleteCommand(%q) = %q, expected %q", tt.q, c, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	type testParam struct {
+		strs     []string
+		expected string
+	}
+	tests := []testParam{
+		{[]string{}, ""},
+		{[]string{"set"}, "set"},
+		{[]string{"set number on", "set number off"}, "set number o"},
+		{[]string{"w ", "wq "}, "w"},
+		{[]string{"w", "q"}, ""},
+	}
+	for _, tt := range tests {
+		if p := commonPrefix(tt.strs); p != tt.expected {
+			t.Errorf("commonPrefix(%q) = %q, expected %q", tt.strs, p, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CONFIG_NAME is the directory for gram within each configuration directory.
+const CONFIG_NAME = "gram"
+
+// SYSTEM_CONFIG_DIR is the system-wide configuration directory used if $XDG_CONFIG_DIRS is unset.
+const SYSTEM_CONFIG_DIR = "/etc/xdg"
+
+const SYNTAX_FILE = "syntax.json"
+const COLOURS_FILE = "colours.json"
+
+// ConfigDir for gram within the user's configuration directory (i.e. $XDG_CONFIG_HOME/gram). Files written by gram,
+// such as history, are stored here.
+func ConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, CONFIG_NAME), nil
+}
+
+// ConfigDirs gram reads configuration from, in order of precedence: $XDG_CONFIG_HOME/gram, ~/.config/gram, then each
+// of $XDG_CONFIG_DIRS/gram (by default /etc/xdg/gram).
+func ConfigDirs() []string {
+	dirs := make([]string, 0)
+	if dir, err := ConfigDir(); err == nil {
+		dirs = append(dirs, dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config", CONFIG_NAME))
+	}
+
+	system := os.Getenv("XDG_CONFIG_DIRS")
+	if len(system) == 0 {
+		system = SYSTEM_CONFIG_DIR
+	}
+	for _, dir := range strings.Split(system, ":") {
+		if len(dir) > 0 {
+			dirs = append(dirs, filepath.Join(dir, CONFIG_NAME))
+		}
+	}
+
+	// Remove duplicates, i.e. when $XDG_CONFIG_HOME is ~/.config
+	unique := make([]string, 0, len(dirs))
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		if !seen[dir] {
+			seen[dir] = true
+			unique = append(unique, dir)
+		}
+	}
+	return unique
+}
+
+// ConfigErrors from parsing configuration files (syntaxes, grammars, colour schemes and keymaps). Missing files aren't
+// errors.
+func ConfigErrors() []error {
+	_, errs := LoadLanguageSyntaxes()
+	_, colourErrs := LoadColourSchemes()
+	_, keymapErrs := LoadKeymap()
+	return append(append(errs, colourErrs...), keymapErrs...)
+}
+
+// isConfigError returns true for errors that should be reported to the user (i.e. not a missing file).
+func isConfigError(err error) bool {
+	return err != nil && !errors.Is(err, os.ErrNotExist)
+}
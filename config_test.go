@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setConfigDirs to new temporary directories, returning the user, home and system config directories for gram.
+func setConfigDirs(t *testing.T) (string, string, string) {
+	user, home, system := t.TempDir(), t.TempDir(), t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", user)
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_DIRS", system)
+	return filepath.Join(user, CONFIG_NAME), filepath.Join(home, ".config", CONFIG_NAME), filepath.Join(system, CONFIG_NAME)
+}
+
+func writeConfigFile(t *testing.T, dir, name, content string) {
+	os.MkdirAll(dir, 0755)
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigDirs(t *testing.T) {
+	user, home, system := setConfigDirs(t)
+	if dirs := ConfigDirs(); !reflect.DeepEqual(dirs, []string{user, home, system}) {
+		t.Errorf("ConfigDirs() = %v, expected %v", dirs, []string{user, home, system})
+	}
+}
+
+func TestLoadLanguageSyntaxesMergesConfig(t *testing.T) {
+	user, home, system := setConfigDirs(t)
+	writeConfigFile(t, system, SYNTAX_FILE, `[{"name": "go", "extensions": [".go"], "keywords": ["system"]}]`)
+	writeConfigFile(t, home, SYNTAX_FILE, `[{"name": "zig", "extensions": [".zig"]}]`)
+	writeConfigFile(t, user, SYNTAX_FILE, `[{"name": "go", "extensions": [".go"], "keywords": ["user"]}]`)
+
+	syntaxes, errs := LoadLanguageSyntaxes()
+	if len(errs) != 0 {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+	if l := GetLanguageSyntax("main.go", nil); !reflect.DeepEqual(l.Keywords, []string{"user"}) {
+		t.Errorf("Expected user syntax to take precedence, got keywords %v", l.Keywords)
+	}
+	if _, exists := FindLanguageSyntax(syntaxes, "zig"); !exists {
+		t.Errorf("Expected new language from config")
+	}
+	if _, exists := FindLanguageSyntax(syntaxes, "java"); !exists {
+		t.Errorf("Expected builtin languages to remain")
+	}
+
+	n := 0
+	for _, l := range syntaxes {
+		if l.Name == "go" {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("Expected overridden syntax to be replaced, found %d go syntaxes", n)
+	}
+}
+
+func TestLoadLanguageSyntaxesOnce(t *testing.T) {
+	user, _, _ := setConfigDirs(t)
+	writeConfigFile(t, user, SYNTAX_FILE, `[{"name": "zig", "extensions": [".zig"]}]`)
+	if l := GetLanguageSyntax("main.zig", nil); l.Name != "zig" {
+		t.Fatalf("Expected syntax from config, got %q", l.Name)
+	}
+
+	writeConfigFile(t, user, SYNTAX_FILE, `[]`)
+	if l := GetLanguageSyntax("main.zig", nil); l.Name != "zig" {
+		t.Errorf("Expected config to be loaded once, got %q after changing it", l.Name)
+	}
+
+	setConfigDirs(t)
+	if l := GetLanguageSyntax("main.zig", nil); l.Name == "zig" {
+		t.Errorf("Expected config to be loaded from new config directories")
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	user, _, system := setConfigDirs(t)
+	if errs := ConfigErrors(); len(errs) != 0 {
+		t.Errorf("Expected no errors for missing config, got %v", errs)
+	}
+
+	user, _, system = setConfigDirs(t) // Syntaxes are only loaded once from each set of directories
+	writeConfigFile(t, user, SYNTAX_FILE, `[{"name": `)
+	writeConfigFile(t, system, COLOURS_FILE, `{`)
+	if errs := ConfigErrors(); len(errs) != 2 {
+		t.Errorf("Expected 2 errors for malformed config, got %v", errs)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Number of rows, at the start and end of a file, searched for modelines.
+const MODELINE_ROWS = 5
+
+var vimModeline = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex):.*?\b(?:ft|filetype|syntax|syn)=([\w+-]+)`)
+var emacsModeline = regexp.MustCompile(`-\*-(.*?)-\*-`)
+
+// loadedSyntaxes of the configuration directories last loaded from, so that configuration is only read and parsed once
+// rather than each time a syntax is created.
+var loadedSyntaxes struct {
+	dirs     string
+	syntaxes []LanguageSyntax
+	errs     []error
+}
+
+// LoadLanguageSyntaxes available to the editor, in order of precedence. Definitions in each of ConfigDirs, both
+// syntax.json then grammars, are merged over those of lower precedence directories, then builtins. Errors from
+// malformed files are returned alongside the syntaxes that did load. Files are read once for each set of ConfigDirs.
+func LoadLanguageSyntaxes() ([]LanguageSyntax, []error) {
+	dirs := ConfigDirs()
+	key := strings.Join(dirs, string(filepath.ListSeparator))
+	if loadedSyntaxes.syntaxes == nil || loadedSyntaxes.dirs != key {
+		loadedSyntaxes.syntaxes, loadedSyntaxes.errs = loadLanguageSyntaxes(dirs)
+		loadedSyntaxes.dirs = key
+	}
+	return append([]LanguageSyntax{}, loadedSyntaxes.syntaxes...), append([]error{}, loadedSyntaxes.errs...)
+}
+
+// loadLanguageSyntaxes from dirs, in order of precedence, merged over the builtins.
+func loadLanguageSyntaxes(dirs []string) ([]LanguageSyntax, []error) {
+	syntaxes := append([]LanguageSyntax{}, builtinLanguageSyntaxs...)
+	errs := make([]error, 0)
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		grammars, grammarErrs := LoadGrammarsFromDir(filepath.Join(dirs[i], GRAMMAR_DIR))
+		syntaxes = MergeLanguageSyntaxes(syntaxes, grammars)
+		errs = append(errs, grammarErrs...)
+
+		user, err := LoadSyntaxesFromFile(filepath.Join(dirs[i], SYNTAX_FILE))
+		if isConfigError(err) {
+			errs = append(errs, err)
+		}
+		syntaxes = MergeLanguageSyntaxes(syntaxes, user)
+	}
+	return syntaxes, errs
+}
+
+// MergeLanguageSyntaxes with overrides taking precedence over base. An override replaces any base syntax with the
+// same name.
+func MergeLanguageSyntaxes(base, overrides []LanguageSyntax) []LanguageSyntax {
+	names := make(map[string]bool)
+	for _, l := range overrides {
+		if len(l.Name) > 0 {
+			names[strings.ToLower(l.Name)] = true
+		}
+	}
+
+	merged := append([]LanguageSyntax{}, overrides...)
+	for _, l := range base {
+		if !names[strings.ToLower(l.Name)] || len(l.Name) == 0 {
+			merged = append(merged, l)
+		}
+	}
+	return merged
+}
+
+// GetLanguageSyntax of file, detected from its filename and contents (may be nil). In order of priority: a vim or emacs
+// modeline, the exact filename, the longest matching file extension, then the shebang's interpreter. Ties are won by
+// the syntax with higher precedence in LoadLanguageSyntaxes.
+func GetLanguageSyntax(filename string, rows []Row) LanguageSyntax {
+	syntaxes, _ := LoadLanguageSyntaxes()
+
+	if name, exists := DetectModeline(rows); exists {
+		if l, exists := FindLanguageSyntax(syntaxes, name); exists {
+			return l
+		}
+	}
+
+	base := filepath.Base(filename)
+	for _, l := range syntaxes {
+		for _, f := range l.Filenames {
+			if f == base {
+				return l
+			}
+		}
+	}
+
+	match, matchLen := defaultSyntax, 0
+	for _, l := range syntaxes {
+		for _, ext := range l.Exts {
+			if len(ext) > matchLen && FileHasExtension(filename, []string{ext}) {
+				match, matchLen = l, len(ext)
+			}
+		}
+	}
+	if matchLen > 0 {
+		return match
+	}
+
+	if interpreter, exists := DetectShebang(rows); exists {
+		for _, l := range syntaxes {
+			if hasInterpreter(l, interpreter) {
+				return l
+			}
+		}
+	}
+	return defaultSyntax
+}
+
+// GetLanguageSyntaxByName, as used in modelines, i.e. "python", "py" or "python3".
+func GetLanguageSyntaxByName(name string) (LanguageSyntax, bool) {
+	syntaxes, _ := LoadLanguageSyntaxes()
+	return FindLanguageSyntax(syntaxes, name)
+}
+
+// FindLanguageSyntax by name. Names match a syntax's name, an extension (without '.') or an interpreter.
+func FindLanguageSyntax(syntaxes []LanguageSyntax, name string) (LanguageSyntax, bool) {
+	name = strings.ToLower(name)
+	if name == defaultSyntax.Name {
+		return defaultSyntax, true
+	}
+	for _, l := range syntaxes {
+		if strings.ToLower(l.Name) == name {
+			return l, true
+		}
+	}
+	for _, l := range syntaxes {
+		for _, ext := range l.Exts {
+			if strings.TrimPrefix(ext, ".") == name {
+				return l, true
+			}
+		}
+		if hasInterpreter(l, name) {
+			return l, true
+		}
+	}
+	return LanguageSyntax{}, false
+}
+
+// FileHasExtension returns true if filename ends with any of the non-empty extensions.
+func FileHasExtension(filename string, exts []string) bool {
+	for _, ext := range exts {
+		if len(ext) > 0 && strings.HasSuffix(filename, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectModeline returns the language set by a vim (`vim: ft=python`) or emacs (`-*- mode: python -*-`) modeline.
+func DetectModeline(rows []Row) (string, bool) {
+	for i, r := range rows {
+		if i >= MODELINE_ROWS && i < len(rows)-MODELINE_ROWS {
+			continue
+		}
+		l := r.Render()
+		if m := vimModeline.FindStringSubmatch(l); m != nil {
+			return m[1], true
+		}
+		if m := emacsModeline.FindStringSubmatch(l); m != nil {
+			if mode, exists := emacsMode(m[1]); exists {
+				return mode, true
+			}
+		}
+	}
+	return "", false
+}
+
+// emacsMode from the contents of an emacs modeline. Either a lone mode, or `var: value;` pairs.
+func emacsMode(x string) (string, bool) {
+	if !strings.Contains(x, ":") {
+		mode := strings.TrimSpace(x)
+		return mode, len(mode) > 0
+	}
+	for _, kv := range strings.Split(x, ";") {
+		k, v, _ := strings.Cut(kv, ":")
+		if strings.TrimSpace(k) == "mode" {
+			return strings.TrimSpace(v), true
+		}
+	}
+	return "", false
+}
+
+// DetectShebang returns the interpreter of a shebang on the first row, i.e. `#!/usr/bin/env python3` is "python3".
+func DetectShebang(rows []Row) (string, bool) {
+	if len(rows) == 0 {
+		return "", false
+	}
+	l := rows[0].Render()
+	if !strings.HasPrefix(l, "#!") {
+		return "", false
+	}
+
+	fields := strings.Fields(l[2:])
+	if len(fields) == 0 {
+		return "", false
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") && !strings.Contains(f, "=") {
+				interpreter = filepath.Base(f)
+				break
+			}
+		}
+	}
+	return interpreter, len(interpreter) > 0
+}
+
+// hasInterpreter returns true if the syntax lists the interpreter, ignoring any version suffix (i.e. python3.11).
+func hasInterpreter(l LanguageSyntax, interpreter string) bool {
+	unversioned := strings.TrimRight(interpreter, "0123456789.")
+	for _, i := range l.Interpreters {
+		if i == interpreter || i == unversioned {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package main
+
+import "testing"
+
+func TestGetLanguageSyntax(t *testing.T) {
+	setConfigDirs(t)
+
+	type testParam struct {
+		filename, firstLine, lastLine, expected, description string
+	}
+	tests := []testParam{{
+		filename:    "main.go",
+		expected:    "go",
+		description: "Extension",
+	}, {
+		filename:    "component.tsx",
+		expected:    "typescript",
+		description: "Extension with prefix of another extension",
+	}, {
+		filename:    "Makefile",
+		expected:    "make",
+		description: "Exact filename",
+	}, {
+		filename:    "build/Dockerfile",
+		expected:    "dockerfile",
+		description: "Exact filename in directory",
+	}, {
+		filename:    "script",
+		firstLine:   "#!/usr/bin/env python3",
+		expected:    "python",
+		description: "Shebang with env and versioned interpreter",
+	}, {
+		filename:    "run",
+		firstLine:   "#!/bin/bash -e",
+		expected:    "sh",
+		description: "Shebang with flags",
+	}, {
+		filename:    "script.go",
+		firstLine:   "#!/usr/bin/env python3",
+		expected:    "go",
+		description: "Extension takes priority over shebang",
+	}, {
+		filename:    "notes.txt",
+		lastLine:    "# vim: set ft=python:",
+		expected:    "python",
+		description: "Vim modeline",
+	}, {
+		filename:    "Makefile",
+		firstLine:   "# -*- mode: sh; coding: utf-8 -*-",
+		expected:    "sh",
+		description: "Emacs modeline takes priority over filename",
+	}, {
+		filename:    "notes",
+		firstLine:   "hello",
+		expected:    "text",
+		description: "Unknown file",
+	}, {
+		filename:    "",
+		expected:    "text",
+		description: "No filename",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			rows := []Row{ConstructRow(tt.firstLine)}
+			for i := 0; i < 2*MODELINE_ROWS; i++ {
+				rows = append(rows, ConstructRow(""))
+			}
+			rows = append(rows, ConstructRow(tt.lastLine))
+
+			if l := GetLanguageSyntax(tt.filename, rows); l.Name != tt.expected {
+				t.Errorf("GetLanguageSyntax(%s) = %s, expected %s", tt.filename, l.Name, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetLanguageSyntaxByName(t *testing.T) {
+	setConfigDirs(t)
+	for _, name := range []string{"python", "py", "python3", "Python"} {
+		if l, exists := GetLanguageSyntaxByName(name); !exists || l.Name != "python" {
+			t.Errorf("GetLanguageSyntaxByName(%s) = %s, %t, expected python", name, l.Name, exists)
+		}
+	}
+	if _, exists := GetLanguageSyntaxByName("cobol"); exists {
+		t.Errorf("Expected no syntax for unknown language")
+	}
+}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"golang.org/x/sys/unix"
 )
@@ -11,31 +17,53 @@ type Cmd rune
 
 const STATUS_BAR = 1
 
+// STATUS_MESSAGE_TIMEOUT is how long a status message replaces the status bar.
+const STATUS_MESSAGE_TIMEOUT = 5 * time.Second
+
+// STATUS_COPY_LEN is the number of characters of copied text shown in the status bar.
+const STATUS_COPY_LEN = 20
+
 const (
 	// Must be higher than 128 to avoid clashing with ASCII
-	UP          Cmd = 1000
-	DOWN            = 1001
-	LEFT            = 1002
-	RIGHT           = 1003
-	PAGE_UP         = 1004
-	PAGE_DOWN       = 1005
-	HOME_KEY        = 1006
-	END_KEY         = 1007
-	DELETE          = 1008
-	SHIFT_RIGHT     = 1009
-	SHIFT_LEFT      = 1010
+	UP        Cmd = 1000
+	DOWN          = 1001
+	LEFT          = 1002
+	RIGHT         = 1003
+	PAGE_UP       = 1004
+	PAGE_DOWN     = 1005
+	HOME_KEY      = 1006
+	END_KEY       = 1007
+	DELETE        = 1008
+	INSERT        = 1009
+	F1            = 1010
+	F2            = 1011
+	F3            = 1012
+	F4            = 1013
+	F5            = 1014
+	F6            = 1015
+	F7            = 1016
+	F8            = 1017
+	F9            = 1018
+	F10           = 1019
+	F11           = 1020
+	F12           = 1021
+
+	// Keys with modifiers, see keys.go
+	SHIFT_UP    = UP | MOD_SHIFT
+	SHIFT_DOWN  = DOWN | MOD_SHIFT
+	SHIFT_RIGHT = RIGHT | MOD_SHIFT
+	SHIFT_LEFT  = LEFT | MOD_SHIFT
+	SHIFT_HOME  = HOME_KEY | MOD_SHIFT
+	SHIFT_END   = END_KEY | MOD_SHIFT
+	SHIFT_TAB   = TAB | MOD_SHIFT
+	CTRL_RIGHT  = RIGHT | MOD_CTRL
+	CTRL_LEFT   = LEFT | MOD_CTRL
 
 	// Specific ANSI mappings
-	BACKSPACE     = 127
-	ENTER         = 13
-	SEARCH        = 6  // Ctrl-F on Mac OS
-	UNDO          = 26 // Ctrl-X on Mac OS
-	COPY          = 3  // Ctrl-C on Mac OS
-	PASTE         = 22 // Ctrl-V on Mac OS
-	DELETE_ROW    = 4  // Ctrl-D on Mac OS
-	SAVE          = 19 // Ctrl-S on Mac OS
-	SAVE_AND_EXIT = 17 // Ctrl-Q on Mac OS
-	EXIT          = 23 // Ctrl-W on Mac OS
+	BACKSPACE = 127
+	ENTER     = 13
+	TAB       = 9
+	ESCAPE    = 27
 )
 
 type Editor struct {
@@ -48,7 +76,27 @@ type Editor struct {
 	charHistory          byteRing
 	cmdHistory           *CommandHistory
 	syntax               *Syntax
-	paste                string
+	paste                string    // Internal register of copied text
+	clipboard            Clipboard // Copied text is shared with, see SetClipboard
+	registers            Registers
+	register             byte // Named register selected for the next copy, cut or paste, or 0
+	yank                 *yankState
+	searchHistory        *PromptHistory
+	commandHistory       *PromptHistory
+	statusMsg            string
+	statusMsgTime        time.Time
+	colours              ColourScheme
+	lineNumbers          bool   // Show line numbers in a gutter left of the text
+	selecting            bool   // Text between the anchor and the cursor is selected
+	marking              bool   // Cursor moves extend the selection, after Copy without one
+	ax, ay               uint   // Position in file of selection anchor
+	searchQuery          string // Query of the search in progress, highlighted in visible rows
+	brackets             *BracketMatch
+	keymap               Keymap
+	pending              []byte     // Input read ahead of ReadChar, i.e. after the end of a paste
+	mouse                MouseEvent // Of the last MOUSE key decoded by HandleEscapeCode
+	mouseDown            bool
+	dirty                bool // Rows have been edited since the file was opened or saved
 }
 
 func ConstructEditor(filename string) (Editor, error) {
@@ -68,29 +116,79 @@ func ConstructEditor(filename string) (Editor, error) {
 		rows:        rows,
 		charHistory: *NewbyteRing(10),
 		cmdHistory:  CreateCommandHistory(),
-		syntax:      CreateSyntax(filename),
+		syntax:      CreateSyntax(filename, rows),
 		paste:       "",
+
+		searchHistory:  LoadPromptHistory("search_history"),
+		commandHistory: LoadPromptHistory("command_history"),
+		colours:        GetColourScheme(),
+	}
+	e.keymap, _ = LoadKeymap()
+	e.clipboard, err = DetectClipboard(os.Stdout)
+	if err != nil {
+		e.SetStatusMessage("%s", err)
 	}
 	e.GetWindowSize()
+
+	if errs := ConfigErrors(); len(errs) > 0 {
+		e.SetStatusMessage("Config error (%d total): %s", len(errs), errs[0])
+	}
 	return e, nil
 }
 
+// ErrUnsaved is returned when replacing a document that has been edited since it was saved.
+var ErrUnsaved = errors.New("unsaved changes")
+
+// Open filename in the editor, replacing the current document. Cursor is placed at the start of the file. Returns
+// ErrUnsaved, leaving the document open, if it has unsaved edits.
+func (e *Editor) Open(filename string) error {
+	if e.dirty {
+		return ErrUnsaved
+	}
+	return e.Reopen(filename)
+}
+
+// Reopen filename in the editor, like Open, discarding any unsaved edits, selection and undo history.
+func (e *Editor) Reopen(filename string) error {
+	rows, err := OpenOrCreate(filename)
+	if err != nil {
+		return err
+	}
+	e.rows = rows
+	e.filename = filename
+	e.cx, e.cy = 0, 0
+	e.rowOffset, e.colOffset = 0, 0
+	e.cmdHistory = CreateCommandHistory()
+	e.syntax = CreateSyntax(filename, rows)
+	e.selecting, e.marking, e.ax, e.ay = false, false, 0, 0
+	e.yank, e.mouseDown = nil, false
+	e.dirty = false
+	return nil
+}
+
+// Edited rows from y: the document is unsaved, and highlighting from row y is recomputed.
+func (e *Editor) Edited(y uint) {
+	e.dirty = true
+	e.syntax.Invalidate(y)
+}
+
 func (e *Editor) ShowCursor() {
 	e.MoveCursor(e.cx, e.cy)
 }
 
 func (e *Editor) HideCursor() {
-	fmt.Printf("\x1b[%d;%dL", (e.cy-e.rowOffset)+1, (e.cx-e.colOffset)+1)
+	fmt.Printf("\x1b[%d;%dL", (e.cy-e.rowOffset)+1, (e.cx-e.colOffset)+1+e.GutterWidth())
 }
 
 // MoveCursor to document coordinates (x, y).
 func (e *Editor) MoveCursor(x, y uint) {
 	e.SetScroll()
-	fmt.Printf("\x1b[%d;%dH", (y-e.rowOffset)+1, (x-e.colOffset)+1)
+	fmt.Printf("\x1b[%d;%dH", (y-e.rowOffset)+1, (x-e.colOffset)+1+e.GutterWidth())
 }
 
+// MoveCursorToStatusBar in screen coordinates, regardless of scroll.
 func (e *Editor) MoveCursorToStatusBar() {
-	e.MoveCursor(0, e.wRows-1)
+	fmt.Printf("\x1b[%d;1H", e.wRows)
 }
 
 func (e *Editor) ClearLine() {
@@ -125,6 +223,34 @@ func (e *Editor) GetDocumentRows() uint {
 	return uint(len(e.rows))
 }
 
+// GutterWidth of the line numbers, including their trailing space. 0 if line numbers are hidden.
+func (e *Editor) GutterWidth() uint {
+	if !e.lineNumbers {
+		return 0
+	}
+	return uint(len(strconv.Itoa(len(e.rows)))) + 1
+}
+
+// GetEditorCols available for text, right of the gutter.
+func (e *Editor) GetEditorCols() uint {
+	if e.GutterWidth() >= e.wCols {
+		return 1
+	}
+	return e.wCols - e.GutterWidth()
+}
+
+// Selection between the anchor and the cursor, as start and end coordinates (x, y) in order. Returns false if no
+// text is selected.
+func (e *Editor) Selection() (uint, uint, uint, uint, bool) {
+	if !e.selecting || (e.ax == e.cx && e.ay == e.cy) {
+		return 0, 0, 0, 0, false
+	}
+	if e.ay < e.cy || (e.ay == e.cy && e.ax <= e.cx) {
+		return e.ax, e.ay, e.cx, e.cy, true
+	}
+	return e.cx, e.cy, e.ax, e.ay, true
+}
+
 func (e *Editor) DrawRows() {
 	e.ClearLine()
 	r := e.GetEditorRows()
@@ -135,9 +261,12 @@ func (e *Editor) DrawRows() {
 		nRows = e.GetEditorRows()
 	}
 
-	for _, r := range e.rows[e.rowOffset : e.rowOffset+nRows] {
-		l := r.RenderWithin(e.colOffset, e.wCols-e.colOffset)
-		fmt.Printf("%s\r\n", e.syntax.Highlight(l))
+	e.brackets = nil
+	if m, exists := e.syntax.MatchBracket(e.rows, e.cx, e.cy); exists {
+		e.brackets = &m
+	}
+	for y := e.rowOffset; y < e.rowOffset+nRows; y++ {
+		e.DrawRow(y)
 	}
 
 	// TODO: Temporary fix to address unaddressed, overflow error.
@@ -145,11 +274,77 @@ func (e *Editor) DrawRows() {
 	e.DrawStatusBar()
 }
 
+// DrawRow y of the document, with its line number, and the backgrounds of the current line, search matches and
+// selection.
+func (e *Editor) DrawRow(y uint) {
+	if e.lineNumbers {
+		fmt.Print(C(fmt.Sprintf("%*d ", e.GutterWidth()-1, y+1), e.colours.LineNumbers))
+	}
+	w := e.GetEditorCols()
+	l, hl := e.syntax.ColoursWithin(e.GetRow(y).Render(), e.syntax.StateAt(e.rows, y), e.colOffset, w)
+
+	if bg, exists := e.RowBackground(y, len(l)); exists {
+		hl = OverlayColours(hl, bg)
+	}
+	fmt.Print(ApplyColours(l, hl))
+	if y == e.cy && uint(len(l)) < w {
+		fmt.Print(C(strings.Repeat(" ", int(w)-len(l)), e.colours.CurrentLine))
+	}
+	fmt.Print("\r\n")
+}
+
+// RowBackground returns the background colour of the n visible characters of row y, from the current line, then
+// search matches, matching brackets, then the selection. Returns false if the row has no background.
+func (e *Editor) RowBackground(y uint, n int) ([]Colour, bool) {
+	sx, sy, ex, ey, selected := e.Selection()
+	selected = selected && y >= sy && y <= ey
+	bracketed := e.brackets != nil && (e.brackets.y == y || e.brackets.my == y)
+	if y != e.cy && len(e.searchQuery) == 0 && !selected && !bracketed {
+		return nil, false
+	}
+
+	bg := make([]Colour, n)
+	fill := func(start, end int, c Colour) {
+		start, end = start-int(e.colOffset), end-int(e.colOffset)
+		if start < 0 {
+			start = 0
+		}
+		fillColour(bg, start, end, c)
+	}
+	if y == e.cy {
+		fill(0, n+int(e.colOffset), e.colours.CurrentLine)
+	}
+	if len(e.searchQuery) > 0 {
+		row := e.GetRow(y)
+		for i := row.RenderIndexOf(e.searchQuery, 0); i != -1; i = row.RenderIndexOf(e.searchQuery, i+1) {
+			fill(i, i+len(e.searchQuery), e.colours.SearchMatch)
+		}
+	}
+	if bracketed {
+		for _, b := range [][2]uint{{e.brackets.x, e.brackets.y}, {e.brackets.mx, e.brackets.my}} {
+			if b[1] == y {
+				fill(int(b[0]), int(b[0])+1, e.colours.Brackets)
+			}
+		}
+	}
+	if selected {
+		start, end := 0, int(e.GetRow(y).RenderLen())
+		if y == sy {
+			start = int(sx)
+		}
+		if y == ey {
+			end = int(ex)
+		}
+		fill(start, end, e.colours.Selection)
+	}
+	return bg, true
+}
+
 func (e *Editor) DrawEmptyRows(r uint) {
 	for i := uint(1); i < r; i++ {
-		fmt.Printf("~\r\n")
+		fmt.Printf("%s\r\n", C("~", e.colours.Tildes))
 	}
-	fmt.Printf("~\r")
+	fmt.Printf("%s\r", C("~", e.colours.Tildes))
 }
 
 func (e *Editor) RefreshScreen() {
@@ -164,6 +359,13 @@ func (e *Editor) RefreshScreen() {
 }
 
 func (e *Editor) ReadChar() byte {
+	if len(e.pending) > 0 {
+		c := e.pending[0]
+		e.pending = e.pending[1:]
+		e.charHistory.Insert(c)
+		return c
+	}
+
 	c := make([]byte, 1)
 	cs, _ := os.Stdin.Read(c)
 	if cs == 0 {
@@ -175,6 +377,10 @@ func (e *Editor) ReadChar() byte {
 }
 
 func (e *Editor) ReadCharBlock() byte {
+	if len(e.pending) > 0 {
+		return e.ReadChar()
+	}
+
 	c := make([]byte, 1)
 	cs, _ := os.Stdin.Read(c)
 	for cs == 0 && c[0] == 0x00 {
@@ -186,20 +392,22 @@ func (e *Editor) ReadCharBlock() byte {
 
 func (e *Editor) KeyPress() bool {
 	x := e.ReadChar()
-	switch x {
-
-	case SAVE_AND_EXIT:
-		e.Save()
-		return true
+	if x == 0x00 {
+		return false // Timed out
+	}
+	c := Cmd(x)
+	if x == '\x1b' {
+		c = e.HandleEscapeCode()
+	}
 
-	case EXIT:
-		return true
+	if bound, exit := e.RunAction(c); bound {
+		return exit
+	}
+	if e.HandleSelection(c) {
+		return false
+	}
 
-	case '\x1b':
-		c := e.HandleEscapeCode()
-		e.HandleMoveCursor(c)
-		e.HandleOtherEscapedCmds(c)
-		break
+	switch c {
 	case BACKSPACE:
 		if e.GetRowLength() == 0 {
 			e.RemoveCurrentRow()
@@ -212,11 +420,15 @@ func (e *Editor) KeyPress() bool {
 			e.cx = e.GetCurrentRow().RenderLen() - l
 
 		} else {
-			e.GetCurrentRow().RemoveCharAt(e.cx)
-			e.HandleMoveCursor(LEFT)
+			row := e.GetCurrentRow()
+			x := row.snapRenderIndex(e.cx - 1) // Start of the removed character, which may be a tab
+			row.RemoveCharAt(e.cx)
+			e.Edited(e.cy)
+			e.cx = x
 		}
 
 	case ENTER:
+		e.DeleteSelection()
 		e.SplitCurrentRow()
 
 		y := e.cy // So that AddCmd had value of current y, not future e.cy
@@ -225,47 +437,69 @@ func (e *Editor) KeyPress() bool {
 			func(e *Editor) error { return fmt.Errorf("REDO unimplemented") },
 		)
 		e.HandleMoveCursor(RIGHT) // Jumps to start of next (newly-created) line.
-	case SEARCH:
-		e.cx, e.cy = e.RunSearch()
 
-	case UNDO:
-		e.cmdHistory.Undo(e)
+	case PASTE_START:
+		e.RunPaste()
 
-	case COPY:
-		e.paste = e.RunCopy()
-	case PASTE:
-		if len(e.paste) > 0 {
-			e.GetCurrentRow().AddCharsAt(e.cx, e.paste)
-		}
-	case DELETE_ROW:
-		e.RemoveCurrentRow()
+	case MOUSE:
+		e.HandleMouse(e.mouse)
 
-	case SAVE:
-		e.Save()
+	default:
+		if x == '\x1b' {
+			e.ClearSelection()
+			e.HandleMoveCursor(c)
+			e.HandleOtherEscapedCmds(c)
+		}
 	}
 
 	if !isControlChar(x) {
+		e.DeleteSelection() // Typing replaces the selection
 		e.GetCurrentRow().AddCharAt(e.cx, x)
+		e.Edited(e.cy)
 		e.HandleMoveCursor(RIGHT)
 	}
 	return false
 }
 
-// TODO: This does more than RemoveCurrentRow
+// JumpToMatchingBracket of the bracket at the cursor.
+func (e *Editor) JumpToMatchingBracket() {
+	if m, exists := e.syntax.MatchBracket(e.rows, e.cx, e.cy); exists {
+		e.cx, e.cy = m.mx, m.my
+	} else {
+		e.SetStatusMessage("No matching bracket")
+	}
+}
+
+// Paste the copied text, or the selected register, at the cursor as one undoable step, replacing the selection.
+func (e *Editor) Paste() {
+	e.pasteText(e.load(), -1)
+}
+
+// RemoveCurrentRow, keeping one empty row if it's the only row. The cursor moves to the end of the row above if the
+// last row is removed.
 func (e *Editor) RemoveCurrentRow() {
-	if (e.cy+1) == e.GetDocumentRows() && e.GetDocumentRows() > 0 {
-		// Last row, just remove
-		e.rows = e.rows[:]
-	} else if e.GetDocumentRows() > 0 {
+	n := e.GetDocumentRows()
+	switch {
+	case n == 0:
+		return
+	case n == 1:
+		e.rows = []Row{ConstructRow("")}
+	case e.cy+1 == n:
+		e.rows = e.rows[:n-1]
+		e.cy--
+		e.cx = e.GetCurrentRow().RenderLen()
+	default:
 		e.rows = append(e.rows[:e.cy], e.rows[e.cy+1:]...)
 	}
+	e.cx = e.GetCurrentRow().snapRenderIndex(e.cx)
+	e.Edited(e.cy)
 }
 
 func (e *Editor) HandleMoveCursor(x Cmd) {
 	switch x {
 	case LEFT:
 		if e.cx != 0 {
-			e.cx -= 1
+			e.cx = e.GetCurrentRow().snapRenderIndex(e.cx - 1)
 
 			// Move left at start of line, go to end of previous line
 		} else if e.cy != 0 {
@@ -281,7 +515,8 @@ func (e *Editor) HandleMoveCursor(x Cmd) {
 				e.cx = 0
 			}
 		} else {
-			e.cx += 1
+			row := e.GetCurrentRow()
+			e.cx = row.getRenderIndex(row.getSrcIndex(e.cx) + 1)
 		}
 		break
 	case UP:
@@ -309,13 +544,13 @@ func (e *Editor) HandleMoveCursor(x Cmd) {
 		break
 	case END_KEY:
 		e.cx = e.GetRowLength()
-		if e.cx > e.wCols {
-			e.colOffset = e.cx - e.wCols
+		if e.cx > e.GetEditorCols() {
+			e.colOffset = e.cx - e.GetEditorCols()
 		}
 		break
-	case SHIFT_RIGHT:
+	case CTRL_RIGHT:
 		e.cx = e.GetCurrentRow().GetNextWordFrom(e.cx, true)
-	case SHIFT_LEFT:
+	case CTRL_LEFT:
 		e.cx = e.GetCurrentRow().GetNextWordFrom(e.cx, false)
 	}
 
@@ -323,12 +558,8 @@ func (e *Editor) HandleMoveCursor(x Cmd) {
 		e.cy = uint(len(e.rows)) - 1
 	}
 
-	rowL := e.GetRowLength()
-	if rowL == 0 {
-		e.cx = 0
-	} else if e.cx > rowL {
-		e.cx = rowL
-	}
+	// Keep the cursor within the row, and not within a tab.
+	e.cx = e.GetCurrentRow().snapRenderIndex(e.cx)
 }
 
 func (e *Editor) SetScroll() {
@@ -340,102 +571,60 @@ func (e *Editor) SetScroll() {
 
 	if e.cx < e.colOffset {
 		e.colOffset = e.cx
-	} else if e.cx >= (e.colOffset + e.wCols) {
-		e.colOffset = e.cx - e.wCols + 1
+	} else if e.cx >= (e.colOffset + e.GetEditorCols()) {
+		e.colOffset = e.cx - e.GetEditorCols() + 1
 	}
 }
 
+// HandleEscapeCode decodes the key of an escape sequence, after its ESC has been read. See DecodeEscape.
 func (e *Editor) HandleEscapeCode() Cmd {
-	a := e.ReadChar()
-	if a == '\x1b' {
-		return '\x1b'
+	c, m := DecodeEvent(e.readEscapeByte)
+	if c == MOUSE {
+		e.mouse = m
 	}
-	b := e.ReadChar()
-	if b == '\x1b' {
-		return '\x1b'
-	}
-
-	if a == '[' {
-		// Page up/down
-		if b >= '0' && b <= '9' {
-			c := e.ReadChar()
-			if c == '\x1b' {
-				return '\x1b'
-			}
+	return c
+}
 
-			if c == 0x7E {
-				switch b {
-				case '1':
-					return HOME_KEY
-				case '3':
-					return DELETE
-				case '4':
-					return END_KEY
-				case '5':
-					return PAGE_UP
-				case '6':
-					return PAGE_DOWN
-				case '7':
-					return HOME_KEY
-				case '8':
-					return END_KEY
-				}
-			}
-			if c == 0x3B {
-				d := e.ReadChar()
-				if d == '\x1b' {
-					return '\x1b'
-				}
-				e := e.ReadChar()
-				if e == '\x1b' {
-					return '\x1b'
-				}
-				if d == 0x32 {
-					switch e {
-					case 0x43:
-						return SHIFT_RIGHT
-					case 0x44:
-						return SHIFT_LEFT
-					}
-				}
-			}
-		}
+// readEscapeByte of an escape sequence. Returns false if the read timed out.
+func (e *Editor) readEscapeByte() (byte, bool) {
+	c := e.ReadChar()
+	return c, c != 0x00
+}
 
-		// Arrow keys
-		switch b {
-		case 'A':
-			return UP
-		case 'B':
-			return DOWN
-		case 'C':
-			return RIGHT
-		case 'D':
-			return LEFT
-		case 'F':
-			return END_KEY
-		case 'H':
-			return HOME_KEY
-		}
-	}
-	if a == 'O' {
-		switch b {
-		case 'H':
-			return HOME_KEY
-		case 'F':
-			return END_KEY
-		}
-	}
-	return '\x1b'
+// SetStatusMessage to show in place of the status bar, for STATUS_MESSAGE_TIMEOUT.
+func (e *Editor) SetStatusMessage(format string, a ...any) {
+	e.statusMsg = fmt.Sprintf(format, a...)
+	e.statusMsgTime = time.Now()
 }
 
 func (e *Editor) DrawStatusBar() {
+	if len(e.statusMsg) > 0 && time.Since(e.statusMsgTime) < STATUS_MESSAGE_TIMEOUT {
+		fmt.Print(C(ConstructRow(e.statusMsg).RenderWithin(0, e.wCols), e.colours.Message))
+		return
+	}
 	y, x := e.GetWindowSize()
+	fmt.Print(C(ConstructRow(e.StatusLine(x, y)).RenderWithin(0, e.wCols), e.colours.StatusBar))
+}
+
+// StatusLine describing the cursor, window of x by y, and the editor's state.
+func (e *Editor) StatusLine(x, y uint) string {
 	r := e.GetCurrentRow()
 	h := e.cmdHistory.Depth()
-	Cprintf("%DarkBlue%STATUS BAR --% (%Blue.d, %Blue.d) of (%Magenta.d, %Magenta.d) %v. Row: %d. History: %d. Copy: %s", e.cx, e.cy, x, y, e.charHistory.GetHistory(), r.RenderLen(), h, e.paste)
+	hits, misses := e.syntax.CacheStats()
+	return fmt.Sprintf("STATUS BAR -- (%d, %d) of (%d, %d) %v. Row: %d. History: %d. Language: %s. Cache: %d/%d hits. Copy: %s", e.cx, e.cy, x, y, e.charHistory.GetHistory(), r.RenderLen(), h, e.syntax.Name(), hits, hits+misses, statusCopy(e.paste))
+}
+
+// statusCopy of copied text, quoted so that newlines and control characters are escaped, and truncated to
+// STATUS_COPY_LEN characters.
+func statusCopy(text string) string {
+	if r := []rune(text); len(r) > STATUS_COPY_LEN {
+		return strconv.Quote(string(r[:STATUS_COPY_LEN])) + "..."
+	}
+	return strconv.Quote(text)
 }
 
 func (e *Editor) Close() error {
+	fmt.Print(BRACKETED_PASTE_OFF, MOUSE_OFF)
 	err := RevertTerminalMode(e.originalTermios)
 	if err != nil {
 		fmt.Println(fmt.Errorf(
@@ -447,10 +636,16 @@ func (e *Editor) Close() error {
 }
 
 func (e *Editor) Save() error {
-	f, err := os.Create(e.filename)
+	return e.SaveAs(e.filename)
+}
+
+// SaveAs filename, which becomes the file being edited once written. Syntax is detected again for a new filename.
+func (e *Editor) SaveAs(filename string) error {
+	f, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 
 	noOfRows := len(e.rows)
 	for i, row := range e.rows {
@@ -462,6 +657,11 @@ func (e *Editor) Save() error {
 			f.Write([]byte{'\n'})
 		}
 	}
+	if filename != e.filename {
+		e.filename = filename
+		e.syntax = CreateSyntax(filename, e.rows)
+	}
+	e.dirty = false
 	return nil
 }
 
@@ -475,6 +675,7 @@ func (e *Editor) HandleOtherEscapedCmds(c Cmd) {
 
 		safeToJoinRow := e.cy+1 < e.GetDocumentRows()
 
+		e.Edited(e.cy)
 		if e.cx+1 < rowL {
 			row.RemoveCharAt(e.cx + 1)
 		} else if rowL == 1 {
@@ -491,6 +692,7 @@ func (e *Editor) HandleOtherEscapedCmds(c Cmd) {
 func (e *Editor) SplitCurrentRow() {
 	a, b := e.GetCurrentRow().SplitAt(e.cx)
 	newRows := []Row{*a, *b}
+	e.Edited(e.cy)
 
 	if (e.cy + 1) >= e.GetDocumentRows() {
 
@@ -508,36 +710,30 @@ func (e *Editor) SplitCurrentRow() {
 // Run Search across file. Return co-ordinate, (x, y) of first result.
 // Will read inputs until an enter is pressed, and will be used as search term.
 func (e *Editor) RunSearch() (uint, uint) {
-	q := make([]byte, 0)
-	b := e.ReadChar()
-
-	for b != ENTER {
-		e.MoveCursorToStatusBar()
-		e.ClearLine()
-		fmt.Printf("SEARCH: %s", string(q))
-
-		if !isControlChar(b) {
-			q = append(q, b)
-		} else if b == BACKSPACE && len(q) > 0 {
-			q = q[:len(q)-1]
-		}
-		b = e.ReadChar()
-
+	q, ok := e.Prompt("SEARCH: ", e.searchHistory)
+	if !ok || len(q) == 0 {
+		return e.cx, e.cy
 	}
+	e.searchQuery = q
+	defer func() { e.searchQuery = "" }()
+
+	// Stops the search goroutine when leaving search before all results are read.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	// Read search results and let user go through results.
-	for r := range SearchRows(e.rows, string(q)) {
+	for r := range SearchRows(ctx, e.rows, q) {
 		// TODO: handle move cursor on page scrolling.
 		e.cx = r.startI
 		e.cy = r.rowI
 		e.RefreshScreen()
 		// Blocking read on input.
-		b = e.ReadChar()
+		b := e.ReadChar()
 		for b == 0x00 {
 			b = e.ReadChar()
 		}
 
-		if b == SEARCH {
+		if e.keymap[Cmd(b)] == ACTION_SEARCH {
 			return r.startI, r.rowI // Exit search mode
 		} else if b == ENTER {
 			continue // Go to next search term
@@ -551,65 +747,155 @@ func (e *Editor) RunSearch() (uint, uint) {
 	return e.cx, e.cy
 }
 
-// JoinRows from b into row a. If a or b index out of range, no action applied.
-func (e *Editor) JoinRows(a, b uint) {
-	if a >= e.GetDocumentRows() || b >= e.GetDocumentRows() {
+// RunGrep prompts for a query, searches all files under the working directory and lists the results. The selected
+// result is opened at its line and column.
+func (e *Editor) RunGrep() {
+	q, ok := e.Prompt("GREP: ", e.searchHistory)
+	if !ok || len(q) == 0 {
 		return
 	}
-	e.rows[a].Append(&e.rows[b])
 
-	// Remove row b
-	if (b + 1) < e.GetDocumentRows() {
-		e.rows = append(e.rows[:b], e.rows[b+1:]...)
+	res, truncated := CollectGrep(".", q, MAX_SEARCH_RESULTS)
+	title := fmt.Sprintf("GREP '%s'", q)
+	if truncated {
+		title = fmt.Sprintf("GREP '%s' (first %d results)", q, len(res))
+	}
+
+	items := make([]string, len(res))
+	for i, r := range res {
+		items[i] = fmt.Sprintf("%s:%d:%d: %s", r.path, r.rowI+1, r.startI+1, strings.TrimSpace(r.rowRef.Render()))
+	}
+	i, ok := e.RunList(title, items)
+	if !ok {
+		return
+	}
+
+	if filepath.Clean(res[i].path) != filepath.Clean(e.filename) {
+		if err := e.Open(res[i].path); err != nil {
+			e.SetStatusMessage("Can't open %s: %s. Save first", res[i].path, err)
+			return
+		}
+	}
+	e.cx, e.cy = res[i].startI, res[i].rowI
+}
+
+// RunSetLanguage prompts for a language name, and highlights the file as that language.
+func (e *Editor) RunSetLanguage() {
+	name, ok := e.Prompt("LANGUAGE: ", nil)
+	if !ok {
+		return
+	}
+	if l, exists := GetLanguageSyntaxByName(name); exists {
+		e.syntax = CreateSyntaxFor(l)
 	} else {
-		e.rows = e.rows[:b]
+		e.SetStatusMessage("Unknown language: %s", name)
 	}
 }
 
-func (e *Editor) RunCopy() string {
-	sx, sy := e.cx, e.cy
-	ex, ey := e.GetCopyEndCoordinates()
-	return e.GetStringBetween(sx, sy, ex, ey)
+// Prompt user for a line of input on the status bar. UP/DOWN recall previous entries from h (may be nil). Returns
+// false if the prompt was cancelled with ESC. Submitted input is added to h.
+func (e *Editor) Prompt(label string, h *PromptHistory) (string, bool) {
+	return e.PromptWithCompletion(label, h, nil)
 }
 
-func (e *Editor) GetCopyEndCoordinates() (uint, uint) {
-	x := e.ReadCharBlock()
-	cmd := e.HandleEscapeCode()
+// PromptWithCompletion is Prompt, completing the input with TAB. complete returns the inputs the current input could
+// be completed to: their common prefix replaces the input, and if there are several, they're listed after it.
+func (e *Editor) PromptWithCompletion(label string, h *PromptHistory, complete func(q string) []string) (string, bool) {
+	q := make([]byte, 0)
+	hint := ""
+	for {
+		e.MoveCursorToStatusBar()
+		e.ClearLine()
+		fmt.Printf("%s%s", label, string(q))
+		if len(hint) > 0 {
+			fmt.Printf("  %s\x1b[%dD", C(hint, e.colours.LineNumbers), len(hint)+2) // Cursor stays after the input
+		}
 
-	// TODO: Use copy-specific equaivalent of HandleEscapeCode to avoid side effects/ changes. And below
-	if x != '\x1b' || cmd == DELETE { // DELETE is only none-move Cmd from HandleEscapeCode
-		return e.cx, e.cy
-	}
-	for true {
-		e.HandleMoveCursor(cmd)
-		e.ShowCursor()
+		b := e.ReadChar()
+		if b != 0x00 {
+			hint = ""
+		}
+		switch {
+		case b == TAB && complete != nil:
+			candidates := complete(string(q))
+			if len(candidates) > 0 {
+				q = []byte(commonPrefix(candidates))
+			}
+			if len(candidates) > 1 {
+				hint = completionHint(candidates)
+			}
+
+		case b == ENTER:
+			if h != nil {
+				if err := h.Add(string(q)); err != nil {
+					e.SetStatusMessage("Couldn't save history: %s", err)
+				}
+			}
+			return string(q), true
+
+		case b == '\x1b':
+			c := e.HandleEscapeCode()
+			if c == '\x1b' {
+				if h != nil {
+					h.Reset()
+				}
+				return "", false
+			}
+			if h == nil {
+				continue
+			}
+			if c == UP {
+				if prev, exists := h.Prev(); exists {
+					q = []byte(prev)
+				}
+			} else if c == DOWN {
+				next, _ := h.Next()
+				q = []byte(next)
+			}
 
-		x = e.ReadCharBlock()
-		cmd = e.HandleEscapeCode()
+		case b == BACKSPACE:
+			if len(q) > 0 {
+				q = q[:len(q)-1]
+			}
 
-		if x != '\x1b' || cmd == DELETE {
-			break
+		case !isControlChar(b):
+			q = append(q, b)
 		}
 	}
+}
 
-	return e.cx, e.cy
+// JoinRows from b into row a. If a or b index out of range, no action applied.
+func (e *Editor) JoinRows(a, b uint) {
+	if a >= e.GetDocumentRows() || b >= e.GetDocumentRows() {
+		return
+	}
+	e.rows[a].Append(&e.rows[b])
+	e.Edited(a)
+
+	// Remove row b
+	if (b + 1) < e.GetDocumentRows() {
+		e.rows = append(e.rows[:b], e.rows[b+1:]...)
+	} else {
+		e.rows = e.rows[:b]
+	}
 }
 
+// GetStringBetween positions (sx, sy) and (ex, ey), as the source text of the rows between them joined by newlines.
 func (e *Editor) GetStringBetween(sx uint, sy uint, ex uint, ey uint) string {
 
 	// Invalid start, end cursors.
 	if sy > ey || (sy == ey && sx > ex) {
 		return ""
 	}
+	first, last := e.GetRow(sy), e.GetRow(ey)
 	if sy == ey {
-		return e.GetRow(sy).Render()[sx:ex]
+		return string(first.src[first.getSrcIndex(sx):first.getSrcIndex(ex)])
 	}
 
-	result := e.GetRow(sy).Render()[sx:]
-	for i := sy + 1; i+1 < ey; i++ {
-		result += e.GetRow(sy).Render()
+	lines := []string{string(first.src[first.getSrcIndex(sx):])}
+	for y := sy + 1; y < ey; y++ {
+		lines = append(lines, string(e.GetRow(y).src))
 	}
-	result += e.GetRow(ey).Render()[:ex]
-
-	return result
+	lines = append(lines, string(last.src[:last.getSrcIndex(ex)]))
+	return strings.Join(lines, "\n")
 }
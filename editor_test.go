@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func renderRows(rows []Row) []string {
+	result := make([]string, len(rows))
+	for i, r := range rows {
+		result[i] = r.Render()
+	}
+	return result
+}
+
+func testEditor(rows ...string) *Editor {
+	e := &Editor{cmdHistory: CreateCommandHistory(), syntax: CreateSyntaxFor(defaultSyntax)}
+	for _, r := range rows {
+		e.rows = append(e.rows, ConstructRow(r))
+	}
+	return e
+}
+
+func TestRowBackground(t *testing.T) {
+	c := DefaultColourScheme(COLOURS_256)
+	rows := []Row{ConstructRow("foo bar foo"), ConstructRow("bar"), ConstructRow("foo")}
+	type testParam struct {
+		description string
+		e           Editor
+		y           uint
+		expected    []Colour
+	}
+	tests := []testParam{
+		{"no background", Editor{rows: rows, cy: 1}, 0, nil},
+		{"current line", Editor{rows: rows, cy: 1}, 1, []Colour{c.CurrentLine, c.CurrentLine, c.CurrentLine}},
+		{
+			"search matches",
+			Editor{rows: rows, cy: 1, searchQuery: "foo"},
+			0,
+			[]Colour{c.SearchMatch, c.SearchMatch, c.SearchMatch, "", "", "", "", "", c.SearchMatch, c.SearchMatch, c.SearchMatch},
+		},
+		{
+			"search matches scrolled",
+			Editor{rows: rows, cy: 1, searchQuery: "foo", colOffset: 7},
+			0,
+			[]Colour{"", c.SearchMatch, c.SearchMatch, c.SearchMatch},
+		},
+		{
+			"selection start",
+			Editor{rows: rows, selecting: true, ax: 4, ay: 0, cx: 1, cy: 2},
+			0,
+			[]Colour{"", "", "", "", c.Selection, c.Selection, c.Selection, c.Selection, c.Selection, c.Selection, c.Selection},
+		},
+		{
+			"selection end over current line",
+			Editor{rows: rows, selecting: true, ax: 4, ay: 0, cx: 1, cy: 2},
+			2,
+			[]Colour{c.Selection, c.CurrentLine, c.CurrentLine},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			tt.e.colours = c
+			n := len(tt.e.rows[tt.y].RenderWithin(tt.e.colOffset, 80))
+			bg, _ := tt.e.RowBackground(tt.y, n)
+			if !reflect.DeepEqual(bg, tt.expected) {
+				t.Errorf("RowBackground(%d) = %q, expected %q", tt.y, bg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSelection(t *testing.T) {
+	e := Editor{selecting: true, ax: 5, ay: 2, cx: 1, cy: 1}
+	if sx, sy, ex, ey, ok := e.Selection(); !ok || sx != 1 || sy != 1 || ex != 5 || ey != 2 {
+		t.Errorf("Selection() = (%d, %d, %d, %d, %t), expected start at cursor", sx, sy, ex, ey, ok)
+	}
+	e.selecting = false
+	if _, _, _, _, ok := e.Selection(); ok {
+		t.Errorf("Expected no selection")
+	}
+}
+
+func TestGetStringBetween(t *testing.T) {
+	e := testEditor("first row", "second", "", "last row")
+	e.rows[1] = Row{src: []byte("\tsecond")}
+	type testParam struct {
+		description    string
+		sx, sy, ex, ey uint
+		expected       string
+	}
+	tests := []testParam{
+		{"within a row", 2, 0, 5, 0, "rst"},
+		{"empty", 3, 0, 3, 0, ""},
+		{"reversed", 3, 0, 1, 0, ""},
+		{"to the end of a row", 6, 0, 9, 0, "row"},
+		{"two rows", 6, 0, 2, 1, "row\n\t"},
+		{"after a tab", 4, 1, 7, 1, "sec"},
+		{"middle rows", 6, 0, 4, 3, "row\n\tsecond\n\nlast"},
+		{"whole rows", 0, 0, 0, 3, "first row\n\tsecond\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			if s := e.GetStringBetween(tt.sx, tt.sy, tt.ex, tt.ey); s != tt.expected {
+				t.Errorf("GetStringBetween(%d, %d, %d, %d) = %q, expected %q", tt.sx, tt.sy, tt.ex, tt.ey, s, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOpenUnsaved(t *testing.T) {
+	setConfigDirs(t)
+	dir := t.TempDir()
+	other := filepath.Join(dir, "other.txt")
+	os.WriteFile(other, []byte("other"), 0644)
+
+	e := testEditor("hello")
+	e.filename = filepath.Join(dir, "hello.txt")
+	e.InsertText(5, 0, " world")
+	if !e.dirty {
+		t.Fatalf("Expected an edit to mark the document unsaved")
+	}
+	if err := e.Open(other); !errors.Is(err, ErrUnsaved) {
+		t.Errorf("Open() of an unsaved document = %v, expected ErrUnsaved", err)
+	}
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"hello world"}) {
+		t.Errorf("Rows after refused Open() = %q", rows)
+	}
+
+	if err := e.Save(); err != nil || e.dirty {
+		t.Fatalf("Save() = %v, dirty %t", err, e.dirty)
+	}
+	if err := e.Open(other); err != nil || e.filename != other {
+		t.Errorf("Open() after Save() = %v, filename %q", err, e.filename)
+	}
+}
+
+func TestSaveKeepsTabs(t *testing.T) {
+	setConfigDirs(t)
+	type testParam struct {
+		description string
+		raw         string
+		insert      string // Inserted at the start of the file before saving
+		expected    string
+	}
+	tests := []testParam{
+		{"indented", "func f() {\n\tx := 1\n}", "", "func f() {\n\tx := 1\n}"},
+		{"within a row", "a\tb\t\tc", "", "a\tb\t\tc"},
+		{"mixed indentation", "\t  x\n    \ty", "", "\t  x\n    \ty"},
+		{"trailing newline", "\tx\n", "", "\tx\n"},
+		{"inserted tab", "x", "\t", "\tx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "a.go")
+			os.WriteFile(file, []byte(tt.raw), 0644)
+
+			e := testEditor()
+			if err := e.Open(file); err != nil {
+				t.Fatal(err)
+			}
+			e.InsertText(0, 0, tt.insert)
+			if err := e.Save(); err != nil {
+				t.Fatal(err)
+			}
+			if b, _ := os.ReadFile(file); string(b) != tt.expected {
+				t.Errorf("Saved %q, expected %q", b, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStatusLineCacheStats(t *testing.T) {
+	e := testEditor("x := 1")
+	e.charHistory = *NewbyteRing(1)
+	for i := 0; i < 2; i++ {
+		e.syntax.ColoursWithin(e.rows[0].Render(), HlState{}, 0, 80)
+	}
+	if s := e.StatusLine(80, 24); !strings.Contains(s, "Cache: 1/2 hits") {
+		t.Errorf("Expected the status line to show the highlight cache stats, got %q", s)
+	}
+}
+
+func TestStatusLineCopy(t *testing.T) {
+	type testParam struct {
+		paste, expected string
+	}
+	tests := []testParam{
+		{"abc", `Copy: "abc"`},
+		{"abc\n", `Copy: "abc\n"`},
+		{"a\tb\x1b[H", `Copy: "a\tb\x1b[H"`},
+		{strings.Repeat("é", STATUS_COPY_LEN+1), `Copy: "` + strings.Repeat("é", STATUS_COPY_LEN) + `"...`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.paste, func(t *testing.T) {
+			e := testEditor("")
+			e.charHistory = *NewbyteRing(1)
+			e.paste = tt.paste
+			if s := e.StatusLine(80, 24); !strings.HasSuffix(s, tt.expected) {
+				t.Errorf("Expected the status line to end with %s, got %q", tt.expected, s)
+			}
+		})
+	}
+}
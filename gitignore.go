@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignorePattern is a single line of a .gitignore file, relative to the directory of that file.
+type ignorePattern struct {
+	base     string // Directory containing the .gitignore
+	pattern  string
+	negate   bool // Pattern started with '!'
+	dirOnly  bool // Pattern ended with '/'
+	anchored bool // Pattern contains '/', so matches against path relative to base, not just its name.
+}
+
+// IgnoreRules from the .gitignore files found whilst walking a directory tree.
+type IgnoreRules struct {
+	patterns []ignorePattern
+}
+
+// AddFile parses the .gitignore within dir, if one exists.
+func (ig *IgnoreRules) AddFile(dir string) {
+	raw, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+
+	for _, l := range strings.Split(string(raw), "\n") {
+		l = strings.TrimRight(l, " \r")
+		if len(l) == 0 || strings.HasPrefix(l, "#") {
+			continue
+		}
+		p := ignorePattern{base: dir}
+		if strings.HasPrefix(l, "!") {
+			p.negate = true
+			l = l[1:]
+		}
+		if strings.HasSuffix(l, "/") {
+			p.dirOnly = true
+			l = strings.TrimSuffix(l, "/")
+		}
+		if strings.Contains(l, "/") {
+			p.anchored = true
+			l = strings.TrimPrefix(l, "/")
+		}
+		p.pattern = l
+		ig.patterns = append(ig.patterns, p)
+	}
+}
+
+// Ignored returns true if path should be skipped. Later patterns take precedence over earlier ones.
+func (ig *IgnoreRules) Ignored(path string, isDir bool) bool {
+	ignored := false
+	for _, p := range ig.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(p.base, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		var matched bool
+		if p.anchored {
+			matched, _ = filepath.Match(p.pattern, filepath.ToSlash(rel))
+		} else {
+			matched, _ = filepath.Match(p.pattern, filepath.Base(path))
+		}
+		if matched {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
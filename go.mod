@@ -2,4 +2,6 @@ module github.com/Jeadie/gram
 
 go 1.18
 
-require golang.org/x/sys v0.0.0-20220829200755-d48e67d00261 // indirect
+require golang.org/x/sys v0.0.0-20220829200755-d48e67d00261
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseGotoTarget of "line[:col]", from 1, as a 0-indexed row and column of a document of n rows, with the cursor on
+// row y. Lines are absolute, relative to the cursor ("+10", "-10"), or a percentage of the document ("50%"). Targets
+// beyond the document are moved to its end. The column is 0 if not given.
+func ParseGotoTarget(target string, y, n uint) (uint, uint, error) {
+	line, col, hasCol := strings.Cut(strings.TrimSpace(target), ":")
+	if n == 0 {
+		n = 1
+	}
+
+	var row int
+	switch {
+	case strings.HasSuffix(line, "%"):
+		p, err := strconv.Atoi(strings.TrimSuffix(line, "%"))
+		if err != nil || p < 0 {
+			return 0, 0, fmt.Errorf("invalid percentage %q", line)
+		}
+		row = int(n-1) * p / 100
+	case strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-"):
+		d, err := strconv.Atoi(line)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid offset %q", line)
+		}
+		row = int(y) + d
+	default:
+		l, err := strconv.Atoi(line)
+		if err != nil || l < 1 {
+			return 0, 0, fmt.Errorf("invalid line %q", line)
+		}
+		row = l - 1
+	}
+	if row < 0 {
+		row = 0
+	} else if row >= int(n) {
+		row = int(n) - 1
+	}
+
+	if !hasCol {
+		return uint(row), 0, nil
+	}
+	c, err := strconv.Atoi(col)
+	if err != nil || c < 1 {
+		return 0, 0, fmt.Errorf("invalid column %q", col)
+	}
+	return uint(row), uint(c - 1), nil
+}
+
+// Goto target, as parsed by ParseGotoTarget, moving the cursor there and centering it in the view. Columns count
+// characters in the file, so are moved past tabs as rendered.
+func (e *Editor) Goto(target string) error {
+	y, x, err := ParseGotoTarget(target, e.cy, e.GetDocumentRows())
+	if err != nil {
+		return err
+	}
+	if e.GetDocumentRows() == 0 {
+		return nil
+	}
+
+	e.cx, e.cy = e.GetRow(y).getRenderIndex(int(x)), y
+	e.CenterView()
+	return nil
+}
+
+// CenterView on the cursor's row, when the document is taller than the editor, then scrolls to its column.
+func (e *Editor) CenterView() {
+	half := e.GetEditorRows() / 2
+	if e.cy < half {
+		e.rowOffset = 0
+	} else {
+		e.rowOffset = e.cy - half
+	}
+	if e.rowOffset+e.GetEditorRows() > e.GetDocumentRows() && e.GetDocumentRows() > e.GetEditorRows() {
+		e.rowOffset = e.GetDocumentRows() - e.GetEditorRows() // Don't scroll past the end
+	}
+	e.colOffset = 0
+	e.SetScroll()
+}
+
+// RunGoto prompts for a target, and moves the cursor to it.
+func (e *Editor) RunGoto() {
+	target, ok := e.Prompt("GOTO (line[:col], +n, -n or n%): ", nil)
+	if !ok || len(strings.TrimSpace(target)) == 0 {
+		return
+	}
+	if err := e.Goto(target); err != nil {
+		e.SetStatusMessage("Goto: %s", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGotoTarget(t *testing.T) {
+	type testParam struct {
+		target string
+		y, x   uint
+		valid  bool
+	}
+	// Cursor on row 50 of 100.
+	tests := []testParam{
+		{"1", 0, 0, true},
+		{"120", 99, 0, true},
+		{"20:5", 19, 4, true},
+		{" 20:5 ", 19, 4, true},
+		{"+10", 60, 0, true},
+		{"-10:3", 40, 2, true},
+		{"-80", 0, 0, true},
+		{"+80", 99, 0, true},
+		{"0%", 0, 0, true},
+		{"50%", 49, 0, true},
+		{"100%", 99, 0, true},
+		{"200%", 99, 0, true},
+		{"0", 0, 0, false},
+		{"x", 0, 0, false},
+		{"10:", 0, 0, false},
+		{"10:0", 0, 0, false},
+		{"+x", 0, 0, false},
+		{"-5%", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			y, x, err := ParseGotoTarget(tt.target, 50, 100)
+			if (err == nil) != tt.valid || y != tt.y || x != tt.x {
+				t.Errorf("ParseGotoTarget(%q) = (%d, %d), %v. Expected (%d, %d), valid: %t", tt.target, y, x, err, tt.y, tt.x, tt.valid)
+			}
+		})
+	}
+}
+
+func TestGoto(t *testing.T) {
+	raw := strings.Repeat("row\n", 49) + "\tx := 1\n" + strings.Repeat("row\n", 49) + "row"
+	type testParam struct {
+		target    string
+		cx, cy    uint
+		rowOffset uint
+	}
+	tests := []testParam{
+		{"50:2", 4, 49, 44}, // On x, after the tab
+		{"50:4", 6, 49, 44},
+		{"2", 0, 1, 0},
+		{"100:9", 3, 99, 90},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			e := testEditor()
+			e.rows = ParseRows([]byte(raw))
+			e.wRows, e.wCols = 11, 40 // 10 rows of text
+			if err := e.Goto(tt.target); err != nil {
+				t.Fatal(err)
+			}
+			if e.cx != tt.cx || e.cy != tt.cy || e.rowOffset != tt.rowOffset {
+				t.Errorf("Goto(%q) cursor (%d, %d), offset %d. Expected (%d, %d), offset %d", tt.target, e.cx, e.cy, e.rowOffset, tt.cx, tt.cy, tt.rowOffset)
+			}
+		})
+	}
+}
@@ -7,14 +7,23 @@ import (
 
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "No file was specified. Usage: `gram <FILENAME>`")
+		fmt.Fprintf(os.Stderr, "No file was specified. %s", USAGE)
 		return
 	}
-	filename := os.Args[1]
+	filename, target, err := ParseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	e, err := ConstructEditor(filename)
 	if err != nil {
 		Exit(e, err)
 	}
+	if len(target) > 0 {
+		if err := e.Goto(target); err != nil {
+			e.SetStatusMessage("Goto %s: %s", target, err)
+		}
+	}
 
 	// TODO: refactor EnableRawMode() into Editor struct function
 	t, err := EnableRawMode()
@@ -23,6 +32,7 @@ func main() {
 	if err != nil {
 		Exit(e, err)
 	}
+	fmt.Print(BRACKETED_PASTE_ON, MOUSE_ON)
 
 	for !e.KeyPress() {
 		e.RefreshScreen()
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Directory, within ConfigDir, of TextMate (.tmLanguage.json) and Sublime (.sublime-syntax) grammars.
+const GRAMMAR_DIR = "grammars"
+
+// Maximum depth of includes followed when translating a grammar.
+const MAX_GRAMMAR_DEPTH = 8
+
+// grammarRule is a pattern from a TextMate or Sublime grammar, reduced to what gram's highlighting engine supports.
+type grammarRule struct {
+	scope      string
+	match      string
+	group      int
+	begin, end string
+}
+
+// tmGrammar is the subset of a TextMate .tmLanguage.json grammar used by gram.
+type tmGrammar struct {
+	Name       string               `json:"name"`
+	FileTypes  []string             `json:"fileTypes"`
+	Patterns   []tmPattern          `json:"patterns"`
+	Repository map[string]tmPattern `json:"repository"`
+}
+
+type tmPattern struct {
+	Name     string               `json:"name"`
+	Match    string               `json:"match"`
+	Begin    string               `json:"begin"`
+	End      string               `json:"end"`
+	Captures map[string]tmPattern `json:"captures"`
+	Include  string               `json:"include"`
+	Patterns []tmPattern          `json:"patterns"`
+}
+
+// sublimeGrammar is the subset of a .sublime-syntax grammar used by gram.
+type sublimeGrammar struct {
+	Name           string                    `yaml:"name"`
+	FileExtensions []string                  `yaml:"file_extensions"`
+	Variables      map[string]string         `yaml:"variables"`
+	Contexts       map[string][]sublimeEntry `yaml:"contexts"`
+}
+
+type sublimeEntry struct {
+	Match     string               `yaml:"match"`
+	Scope     string               `yaml:"scope"`
+	MetaScope string               `yaml:"meta_scope"`
+	Captures  map[int]string       `yaml:"captures"`
+	Include   string               `yaml:"include"`
+	Push      sublimeContextTarget `yaml:"push"`
+	Pop       bool                 `yaml:"pop"`
+}
+
+// sublimeContextTarget is either the name of a context, or an anonymous context.
+type sublimeContextTarget struct {
+	name    string
+	entries []sublimeEntry
+}
+
+func (t *sublimeContextTarget) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&t.name)
+	}
+	return n.Decode(&t.entries)
+}
+
+// LoadGrammarsFromDir translates every TextMate and Sublime grammar within dir. Grammars that can't be read or
+// parsed are skipped, and their errors returned.
+func LoadGrammarsFromDir(dir string) ([]LanguageSyntax, []error) {
+	syntaxes := make([]LanguageSyntax, 0)
+	errs := make([]error, 0)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return syntaxes, errs
+	}
+
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		var l LanguageSyntax
+		switch {
+		case strings.HasSuffix(e.Name(), ".tmLanguage.json"):
+			l, err = LoadTextMateGrammar(path)
+		case strings.HasSuffix(e.Name(), ".sublime-syntax"):
+			l, err = LoadSublimeGrammar(path)
+		default:
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		syntaxes = append(syntaxes, l)
+	}
+	return syntaxes, errs
+}
+
+// LoadTextMateGrammar from a .tmLanguage.json file.
+func LoadTextMateGrammar(file string) (LanguageSyntax, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return LanguageSyntax{}, err
+	}
+	var g tmGrammar
+	if err = json.Unmarshal(raw, &g); err != nil {
+		return LanguageSyntax{}, err
+	}
+
+	rules := make([]grammarRule, 0)
+	g.collect(g.Patterns, map[string]bool{}, 0, &rules)
+	return translateGrammar(g.Name, g.FileTypes, rules), nil
+}
+
+func (g *tmGrammar) collect(patterns []tmPattern, visited map[string]bool, depth int, rules *[]grammarRule) {
+	if depth > MAX_GRAMMAR_DEPTH {
+		return
+	}
+	for _, p := range patterns {
+		if len(p.Include) > 0 {
+			name := strings.TrimPrefix(p.Include, "#")
+			r, exists := g.Repository[name]
+			if !exists || visited[name] || !strings.HasPrefix(p.Include, "#") {
+				continue
+			}
+			visited[name] = true
+			g.collect([]tmPattern{r}, visited, depth+1, rules)
+			continue
+		}
+
+		switch {
+		case len(p.Match) > 0:
+			scope, group := p.Name, 0
+			if len(scope) == 0 {
+				group, scope = firstCapture(p.Captures)
+			}
+			*rules = append(*rules, grammarRule{scope: scope, match: p.Match, group: group})
+		case len(p.Begin) > 0:
+			*rules = append(*rules, grammarRule{scope: p.Name, begin: p.Begin, end: p.End})
+		default:
+			g.collect(p.Patterns, visited, depth+1, rules)
+		}
+	}
+}
+
+// firstCapture returns the lowest numbered capture group with a scope.
+func firstCapture(captures map[string]tmPattern) (int, string) {
+	groups := make([]int, 0, len(captures))
+	for k, c := range captures {
+		i, err := strconv.Atoi(k)
+		if err == nil && i > 0 && len(c.Name) > 0 {
+			groups = append(groups, i)
+		}
+	}
+	if len(groups) == 0 {
+		return 0, ""
+	}
+	sort.Ints(groups)
+	return groups[0], captures[strconv.Itoa(groups[0])].Name
+}
+
+// LoadSublimeGrammar from a .sublime-syntax file.
+func LoadSublimeGrammar(file string) (LanguageSyntax, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return LanguageSyntax{}, err
+	}
+	// Sublime grammars declare YAML 1.2, which the YAML parser rejects. The subset used by grammars is compatible.
+	raw = yamlDirective.ReplaceAll(raw, nil)
+
+	var g sublimeGrammar
+	if err = yaml.Unmarshal(raw, &g); err != nil {
+		return LanguageSyntax{}, err
+	}
+
+	rules := make([]grammarRule, 0)
+	g.collect(g.Contexts["main"], map[string]bool{"main": true}, 0, &rules)
+	return translateGrammar(g.Name, g.FileExtensions, rules), nil
+}
+
+func (g *sublimeGrammar) collect(entries []sublimeEntry, visited map[string]bool, depth int, rules *[]grammarRule) {
+	if depth > MAX_GRAMMAR_DEPTH {
+		return
+	}
+	for _, e := range entries {
+		if len(e.Include) > 0 {
+			if !visited[e.Include] {
+				visited[e.Include] = true
+				g.collect(g.Contexts[e.Include], visited, depth+1, rules)
+			}
+			continue
+		}
+		if len(e.Match) == 0 || e.Pop {
+			continue
+		}
+
+		match := g.expand(e.Match)
+		pushed := e.Push.entries
+		if len(e.Push.name) > 0 {
+			pushed = g.Contexts[e.Push.name]
+		}
+		if len(pushed) > 0 {
+			// A pushed context is a region, from this match until the context is popped.
+			scope, end := e.Scope, ""
+			for _, p := range pushed {
+				if len(p.MetaScope) > 0 {
+					scope = p.MetaScope
+				}
+				if p.Pop && len(end) == 0 {
+					end = g.expand(p.Match)
+				}
+			}
+			*rules = append(*rules, grammarRule{scope: scope, begin: match, end: end})
+			continue
+		}
+
+		scope, group := e.Scope, 0
+		if len(scope) == 0 {
+			for i, c := range e.Captures {
+				if len(c) > 0 && (group == 0 || i < group) {
+					group, scope = i, c
+				}
+			}
+		}
+		*rules = append(*rules, grammarRule{scope: scope, match: match, group: group})
+	}
+}
+
+var yamlDirective = regexp.MustCompile(`(?m)^%YAML.*$`)
+
+var sublimeVariable = regexp.MustCompile(`\{\{(\w+)\}\}`)
+
+// expand {{variables}} within a Sublime regex.
+func (g *sublimeGrammar) expand(re string) string {
+	for i := 0; i < MAX_GRAMMAR_DEPTH && strings.Contains(re, "{{"); i++ {
+		re = sublimeVariable.ReplaceAllStringFunc(re, func(v string) string {
+			return g.Variables[v[2:len(v)-2]]
+		})
+	}
+	return re
+}
+
+// translateGrammar rules into a LanguageSyntax. Regions with literal delimiters become comments or strings; other
+// matches become regex rules. Rules with scopes not mapped to a colour, or regexes unsupported by Go, are dropped.
+func translateGrammar(name string, exts []string, rules []grammarRule) LanguageSyntax {
+	l := LanguageSyntax{Name: strings.ToLower(name), HlStrings: true, HlNumbers: true}
+	for _, ext := range exts {
+		l.Exts = append(l.Exts, "."+strings.TrimPrefix(ext, "."))
+	}
+
+	for _, r := range rules {
+		if len(r.begin) > 0 {
+			begin, okB := regexLiteral(r.begin)
+			end, okE := regexLiteral(r.end)
+			isLineEnd := r.end == "$" || r.end == "\\n" || r.end == "(?=$)" || r.end == "(?=\\n)" || r.end == ""
+			switch {
+			case !okB || len(begin) == 0:
+			case strings.HasPrefix(r.scope, "comment") && isLineEnd:
+				if len(l.Comment) == 0 {
+					l.Comment = begin
+				}
+			case strings.HasPrefix(r.scope, "comment") && okE:
+				l.BlockComments = append(l.BlockComments, Delimiters{Start: begin, End: end})
+			case strings.HasPrefix(r.scope, "string") && okE && begin == end && len(begin) == 1:
+				l.StringChars = append(l.StringChars, begin)
+			case strings.HasPrefix(r.scope, "string") && okE && len(end) > 0:
+				l.MultilineStrings = append(l.MultilineStrings, Delimiters{Start: begin, End: end})
+			}
+			continue
+		}
+
+		if strings.HasPrefix(r.scope, "comment.line") && len(l.Comment) == 0 {
+			if c, ok := regexLiteral(strings.TrimSuffix(strings.TrimSuffix(r.match, "$"), ".*")); ok && len(c) > 0 {
+				l.Comment = c
+				continue
+			}
+		}
+		if slot := scopeSlot(r.scope); len(slot) > 0 {
+			l.Rules = append(l.Rules, RegexRule{Pattern: r.match, Colour: slot, Group: r.group})
+		}
+	}
+	return l
+}
+
+// scopeSlot maps a TextMate scope to the name of a ColourScheme slot. Returns "" for unmapped scopes.
+func scopeSlot(scope string) string {
+	slots := []struct{ prefix, slot string }{
+		{"comment", "Comments"},
+		{"string", "Strings"},
+		{"constant.numeric", "Numbers"},
+		{"constant.character", "Chars"},
+		{"constant", "Constants"},
+		{"variable.language", "Constants"},
+		{"support.function", "Builtins"},
+		{"entity.name.function", "Functions"},
+		{"meta.function-call", "Functions"},
+		{"storage.type.annotation", "Decorators"},
+		{"meta.annotation", "Decorators"},
+		{"meta.decorator", "Decorators"},
+		{"entity.name.decorator", "Decorators"},
+		{"storage.type", "Types"},
+		{"support.type", "Types"},
+		{"support.class", "Types"},
+		{"entity.name.type", "Types"},
+		{"keyword", "Keyword"},
+		{"storage", "Keyword"},
+	}
+	for _, s := range slots {
+		if strings.HasPrefix(scope, s.prefix) {
+			return s.slot
+		}
+	}
+	return ""
+}
+
+// regexLiteral returns the literal text matched by re, if re has no unescaped metacharacters.
+func regexLiteral(re string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(re); i++ {
+		c := re[i]
+		if c == '\\' && i+1 < len(re) {
+			i++
+			if isIdentChar(re[i]) {
+				return "", false // Character class, i.e. \s, \w
+			}
+			b.WriteByte(re[i])
+			continue
+		}
+		if strings.IndexByte(".[]()*+?{}|^$", c) != -1 {
+			return "", false
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), true
+}
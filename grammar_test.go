@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testTextMateGrammar = `{
+  "name": "Rust",
+  "fileTypes": ["rs"],
+  "patterns": [
+    {"include": "#comments"},
+    {"name": "string.quoted.double.rust", "begin": "\"", "end": "\""},
+    {"name": "keyword.control.rust", "match": "\\b(fn|let|if)\\b"},
+    {"match": "\\b([a-z_]+)\\s*\\(", "captures": {"1": {"name": "entity.name.function.rust"}}},
+    {"name": "invalid.lookbehind", "match": "(?<=x)y"}
+  ],
+  "repository": {
+    "comments": {
+      "patterns": [
+        {"name": "comment.line.double-slash.rust", "match": "//.*$"},
+        {"name": "comment.block.rust", "begin": "/\\*", "end": "\\*/"}
+      ]
+    }
+  }
+}`
+
+const testSublimeGrammar = `%YAML 1.2
+---
+file_extensions: [sql]
+variables:
+  ident: '[A-Za-z_]+'
+contexts:
+  main:
+    - include: comments
+    - match: '\b(?i:select|from)\b'
+      scope: keyword.other.sql
+    - match: '({{ident}})\('
+      captures:
+        1: support.function.sql
+    - match: "'"
+      push: string
+  comments:
+    - match: '--'
+      push:
+        - meta_scope: comment.line.sql
+        - match: $
+          pop: true
+  string:
+    - meta_scope: string.quoted.single.sql
+    - match: "'"
+      pop: true
+`
+
+func TestLoadGrammarsFromDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "rust.tmLanguage.json"), []byte(testTextMateGrammar), 0644)
+	os.WriteFile(filepath.Join(dir, "sql.sublime-syntax"), []byte(testSublimeGrammar), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.tmLanguage.json"), []byte("{"), 0644)
+
+	syntaxes, errs := LoadGrammarsFromDir(dir)
+	if len(syntaxes) != 2 {
+		t.Fatalf("Expected 2 grammars, got %d", len(syntaxes))
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected 1 error from broken grammar, got %v", errs)
+	}
+
+	rust := syntaxes[0]
+	expected := LanguageSyntax{
+		Name:          "rust",
+		Exts:          []string{".rs"},
+		Comment:       "//",
+		BlockComments: []Delimiters{{Start: "/*", End: "*/"}},
+		StringChars:   []string{"\""},
+		Rules: []RegexRule{
+			{Pattern: "\\b(fn|let|if)\\b", Colour: "Keyword"},
+			{Pattern: "\\b([a-z_]+)\\s*\\(", Colour: "Functions", Group: 1},
+		},
+		HlStrings: true,
+		HlNumbers: true,
+	}
+	if !reflect.DeepEqual(rust, expected) {
+		t.Errorf("Unexpected TextMate translation:\n got      %+v\n expected %+v", rust, expected)
+	}
+
+	sql := syntaxes[1]
+	expected = LanguageSyntax{
+		Exts:        []string{".sql"},
+		Comment:     "--",
+		StringChars: []string{"'"},
+		Rules: []RegexRule{
+			{Pattern: "\\b(?i:select|from)\\b", Colour: "Keyword"},
+			{Pattern: "([A-Za-z_]+)\\(", Colour: "Builtins", Group: 1},
+		},
+		HlStrings: true,
+		HlNumbers: true,
+	}
+	if !reflect.DeepEqual(sql, expected) {
+		t.Errorf("Unexpected Sublime translation:\n got      %+v\n expected %+v", sql, expected)
+	}
+
+	s := &Syntax{l: sql, cache: *CreateCache[highlightKey, []Colour](10), c: DefaultColourScheme(COLOURS_256)}
+	s.rules = s.compileRules()
+	hl, _ := s.Tokenize("SELECT count(x) FROM t -- 'c'", HlState{})
+	if output := tokenClasses(hl, s.c); output != "KKKKKK.BBBBB....KKKK...CCCCCC" {
+		t.Errorf("Unexpected highlighting from grammar: %s", output)
+	}
+}
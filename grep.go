@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const GREP_WORKERS = 8
+
+// Number of bytes inspected when deciding if a file is binary.
+const BINARY_SNIFF_LEN = 8000
+
+type GrepResult struct {
+	SearchResult
+	path string
+}
+
+// GrepFiles concurrently searches for a query in every file under root, skipping binaries and files ignored by
+// .gitignore. Files are searched by a pool of workers, each using SearchRows. Results are returned on a channel,
+// in no particular order, and the channel is closed once all files are searched or ctx is cancelled.
+func GrepFiles(ctx context.Context, root, q string, workers int) <-chan GrepResult {
+	paths := make(chan string)
+	results := make(chan GrepResult)
+
+	go func() {
+		defer close(paths)
+		ig := &IgnoreRules{}
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" || (path != root && ig.Ignored(path, true)) {
+					return filepath.SkipDir
+				}
+				ig.AddFile(path)
+				return nil
+			}
+			if d.Type().IsRegular() && !ig.Ignored(path, false) {
+				select {
+				case paths <- path:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				grepFile(ctx, path, q, results)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// CollectGrep results of GrepFiles, sorted, stopping once limit results are found. Returns true if the results were
+// truncated at limit.
+func CollectGrep(root, q string, limit int) ([]GrepResult, bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	res := make([]GrepResult, 0)
+	for r := range GrepFiles(ctx, root, q, GREP_WORKERS) {
+		if len(res) >= limit {
+			cancel()
+			SortGrepResults(res)
+			return res, true
+		}
+		res = append(res, r)
+	}
+	SortGrepResults(res)
+	return res, false
+}
+
+func grepFile(ctx context.Context, path, q string, results chan<- GrepResult) {
+	raw, err := os.ReadFile(path)
+	if err != nil || isBinary(raw) {
+		return
+	}
+	for r := range SearchRows(ctx, ParseRows(raw), q) {
+		select {
+		case results <- GrepResult{SearchResult: r, path: path}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// isBinary guesses if file contents are binary by the presence of a NUL byte near the start.
+func isBinary(raw []byte) bool {
+	return bytes.IndexByte(raw[:Min(uint(len(raw)), BINARY_SNIFF_LEN)], 0) != -1
+}
+
+// SortGrepResults by path, then position within file.
+func SortGrepResults(res []GrepResult) {
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].path != res[j].path {
+			return res[i].path < res[j].path
+		}
+		if res[i].rowI != res[j].rowI {
+			return res[i].rowI < res[j].rowI
+		}
+		return res[i].startI < res[j].startI
+	})
+}
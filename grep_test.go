@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestGrepFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.go":           "package main\nfunc Go() {}",
+		"sub/b.go":       "// Go is here",
+		"ignored.log":    "Go",
+		"build/c.go":     "Go",
+		"sub/keep.log":   "Go",
+		"binary.bin":     "Go\x00Go",
+		".gitignore":     "*.log\n!keep.log\nbuild/\n",
+		"sub/.gitignore": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res := make([]GrepResult, 0)
+	for r := range GrepFiles(context.Background(), root, "Go", 3) {
+		res = append(res, r)
+	}
+	SortGrepResults(res)
+
+	expected := []struct {
+		path       string
+		row, start uint
+	}{
+		{"a.go", 1, 5},
+		{"sub/b.go", 0, 3},
+		{"sub/keep.log", 0, 0},
+	}
+	if len(res) != len(expected) {
+		t.Fatalf("Expected %d results, got %d: %+v", len(expected), len(res), res)
+	}
+	for i, x := range expected {
+		if res[i].path != filepath.Join(root, x.path) || res[i].rowI != x.row || res[i].startI != x.start {
+			t.Errorf("Unexpected result: %+v, expected %+v", res[i], x)
+		}
+	}
+}
+
+func TestGrepFilesCancelDoesNotLeak(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		os.WriteFile(filepath.Join(root, name), []byte("Go\nGo\nGo"), 0644)
+	}
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		for range GrepFiles(ctx, root, "Go", 3) {
+			break
+		}
+		cancel()
+	}
+	waitForGoroutines(t, before)
+}
+
+func TestCollectGrepLimit(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		os.WriteFile(filepath.Join(root, name), []byte("Go\nGo\nGo"), 0644)
+	}
+	before := runtime.NumGoroutine()
+
+	tests := []struct {
+		limit     int
+		n         int
+		truncated bool
+	}{
+		{20, 12, false},
+		{12, 12, false},
+		{5, 5, true},
+	}
+	for _, tt := range tests {
+		res, truncated := CollectGrep(root, "Go", tt.limit)
+		if len(res) != tt.n || truncated != tt.truncated {
+			t.Errorf("CollectGrep(limit %d) = %d results, truncated %t, expected %d, %t", tt.limit, len(res), truncated, tt.n, tt.truncated)
+		}
+	}
+	waitForGoroutines(t, before)
+}
+
+func TestIgnoredOutsideBase(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	os.MkdirAll(sub, 0755)
+	os.WriteFile(filepath.Join(sub, ".gitignore"), []byte("*.log\n"), 0644)
+	ig := &IgnoreRules{}
+	ig.AddFile(sub)
+
+	tests := []struct {
+		path    string
+		ignored bool
+	}{
+		{filepath.Join(sub, "a.log"), true},
+		{filepath.Join(sub, "..b.log"), true},
+		{filepath.Join(root, "c.log"), false},
+	}
+	for _, tt := range tests {
+		if ig.Ignored(tt.path, false) != tt.ignored {
+			t.Errorf("Ignored(%s) != %t", tt.path, tt.ignored)
+		}
+	}
+}
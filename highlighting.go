@@ -95,6 +95,18 @@ func extractPrintfVerb(t string) (string, string) {
 	return "", ""
 }
 
+// OverlayColours of bg onto the colours of each character in fg. Characters without a background keep their colour.
+func OverlayColours(fg, bg []Colour) []Colour {
+	result := make([]Colour, len(fg))
+	for i, c := range fg {
+		result[i] = c
+		if i < len(bg) && len(bg[i]) > 0 {
+			result[i] = c + bg[i]
+		}
+	}
+	return result
+}
+
 // ApplyColours per character, onto a string.
 func ApplyColours(s string, hl []Colour) string {
 	if len(s) == 0 {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -161,3 +162,228 @@ func TestApplyColours(t *testing.T) {
 		})
 	}
 }
+
+func TestMultilineStates(t *testing.T) {
+	s := &Syntax{
+		l: LanguageSyntax{
+			Comment:          "//",
+			BlockComments:    []Delimiters{{Start: "/*", End: "*/"}},
+			MultilineStrings: []Delimiters{{Start: "`", End: "`"}},
+		},
+		cache: *CreateCache[highlightKey, []Colour](100),
+		c:     DefaultColourScheme(COLOURS_256),
+	}
+	rows := []Row{
+		ConstructRow("x := 1 /* start"),
+		ConstructRow("still a comment"),
+		ConstructRow("end */ y := `raw"),
+		ConstructRow("string`"),
+		ConstructRow("// line comment /*"),
+		ConstructRow("z"),
+	}
+	expected := []HlState{
+		{},
+		{Kind: HL_BLOCK_COMMENT},
+		{Kind: HL_BLOCK_COMMENT},
+		{Kind: HL_MULTILINE_STRING},
+		{},
+		{},
+	}
+	for y, x := range expected {
+		if state := s.StateAt(rows, uint(y)); state != x {
+			t.Errorf("StateAt(%d) = %+v, expected %+v", y, state, x)
+		}
+	}
+
+	if hl := s.Highlight(rows[1].Render(), s.StateAt(rows, 1)); hl != C(rows[1].Render(), s.c.Comments) {
+		t.Errorf("Expected row within block comment to be highlighted as a comment, got %q", hl)
+	}
+
+	// Closing the comment early only changes the states of later rows.
+	rows[0] = ConstructRow("x := 1 /* start */")
+	s.Invalidate(0)
+	if state := s.StateAt(rows, 2); state != (HlState{}) {
+		t.Errorf("StateAt(2) after edit = %+v, expected %+v", state, HlState{})
+	}
+	if state := s.StateAt(rows, 3); state != (HlState{Kind: HL_MULTILINE_STRING}) {
+		t.Errorf("StateAt(3) after edit = %+v, expected %+v", state, HlState{Kind: HL_MULTILINE_STRING})
+	}
+}
+
+func TestMultilineStringsUnhighlighted(t *testing.T) {
+	type testParam struct {
+		hlStrings      bool
+		line, expected string
+		in             HlState
+	}
+	tests := []testParam{
+		{true, "x := `raw", ".....SSSS", HlState{}},
+		{false, "x := `raw", ".........", HlState{}},
+		{true, "string` + y", "SSSSSSS....", HlState{Kind: HL_MULTILINE_STRING}},
+		{false, "string` + y", "...........", HlState{Kind: HL_MULTILINE_STRING}},
+		{false, "/* comment", "CCCCCCCCCC", HlState{}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%t %s", tt.hlStrings, tt.line), func(t *testing.T) {
+			s := &Syntax{
+				l: LanguageSyntax{
+					HlStrings:        tt.hlStrings,
+					BlockComments:    []Delimiters{{Start: "/*", End: "*/"}},
+					MultilineStrings: []Delimiters{{Start: "`", End: "`"}},
+				},
+				c: DefaultColourScheme(COLOURS_256),
+			}
+			hl, _ := s.Tokenize(tt.line, tt.in)
+			if classes := tokenClasses(hl, s.c); classes != tt.expected {
+				t.Errorf("Expected %q. Received %q.", tt.expected, classes)
+			}
+		})
+	}
+}
+
+// tokenClasses summarises highlighting per byte: K(eyword), Y (type), B(uiltin), V (constant), F(unction),
+// D(ecorator), S(tring), H (character), C(omment), N(umber), T(odo) or '.'.
+func tokenClasses(hl []Colour, c ColourScheme) string {
+	classes := map[Colour]byte{
+		c.Keyword: 'K', c.Types: 'Y', c.Builtins: 'B', c.Constants: 'V', c.Functions: 'F', c.Decorators: 'D',
+		c.Strings: 'S', c.Chars: 'H', c.Comments: 'C', c.Numbers: 'N', c.Todos: 'T',
+	}
+	r := make([]byte, len(hl))
+	for i, x := range hl {
+		r[i] = '.'
+		if b, exists := classes[x]; exists && x != "" {
+			r[i] = b
+		}
+	}
+	return string(r)
+}
+
+func TestTokenizeGolden(t *testing.T) {
+	setConfigDirs(t)
+	type testParam struct {
+		filename, line, expected, description string
+	}
+	tests := []testParam{{
+		filename:    "x.go",
+		line:        `if x == "a\"b" {`,
+		expected:    `KK......SSSSSS..`,
+		description: "Go escaped quote doesn't end string",
+	}, {
+		filename:    "x.go",
+		line:        `x := 10 // 20 if`,
+		expected:    `.....NN.CCCCCCCC`,
+		description: "Go no numbers or keywords in comments",
+	}, {
+		filename:    "x.go",
+		line:        `s := "return 1.5"`,
+		expected:    `.....SSSSSSSSSSSS`,
+		description: "Go no keywords or numbers in strings",
+	}, {
+		filename:    "x.go",
+		line:        `x1 := ifx + 2.50`,
+		expected:    `............NNNN`,
+		description: "Go word boundaries",
+	}, {
+		filename:    "x.go",
+		line:        "a := /* if */ `b\\` // TODO c",
+		expected:    ".....CCCCCCCC.SSSS.CCTTTTTTT",
+		description: "Go block comment, raw string and TODO",
+	}, {
+		filename:    "x.py",
+		line:        `def f(): return 'it\'s' # 3`,
+		expected:    `KKK.F....KKKKKK.SSSSSSS.CCC`,
+		description: "Python escaped single quote",
+	}, {
+		filename:    "x.py",
+		line:        `x = """ # not a comment """ + 5`,
+		expected:    `....SSSSSSSSSSSSSSSSSSSSSSS...N`,
+		description: "Python triple quoted string",
+	}, {
+		filename:    "x.java",
+		line:        `int y = 2; /* "s" */ return`,
+		expected:    `YYY.....N..CCCCCCCCC.KKKKKK`,
+		description: "Java block comment containing a string",
+	}, {
+		filename:    "x.js",
+		line:        "let s = `a ${b}` // c",
+		expected:    "KKK.....SSSSSSSS.CCCC",
+		description: "JavaScript template string",
+	}, {
+		filename:    "x.go",
+		line:        `var x error = fmt.Errorf(nil, 'a', 0x1F, 0b10, 1.5e-3, 0xG)`,
+		expected:    `KKK...YYYYY.......FFFFFF.VVV..HHH..NNNN..NNNN..NNNNNN......`,
+		description: "Go keyword groups, function calls, characters and number formats",
+	}, {
+		filename:    "x.py",
+		line:        `@app.route("/") # None`,
+		expected:    `DDDDDDDDDD.SSS..CCCCCC`,
+		description: "Python decorator",
+	}, {
+		filename:    "x.py",
+		line:        `x = len([True, None])`,
+		expected:    `....BBB..VVVV..VVVV..`,
+		description: "Python builtins take precedence over function calls",
+	}, {
+		filename:    "x.java",
+		line:        `@Override char c = 'x';`,
+		expected:    `DDDDDDDDD.YYYY.....HHH.`,
+		description: "Java annotation and character literal",
+	}, {
+		filename:    "x.sh",
+		line:        `if [ "$x" ]; then # fi`,
+		expected:    `KK...SSSS....KKKK.CCCC`,
+		description: "Shell keywords and comments",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			s := CreateSyntax(tt.filename, nil)
+			hl, _ := s.Tokenize(tt.line, HlState{})
+			if output := tokenClasses(hl, s.c); output != tt.expected {
+				t.Errorf("Tokenize(%q)\n got      %s\n expected %s", tt.line, output, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOverlayColours(t *testing.T) {
+	fg := []Colour{Red, Red, "", ""}
+	bg := []Colour{"", Reverse, Reverse}
+	expected := []Colour{Red, Red + Reverse, Reverse, ""}
+	if output := OverlayColours(fg, bg); !reflect.DeepEqual(output, expected) {
+		t.Errorf("OverlayColours() = %q, expected %q", output, expected)
+	}
+}
+
+func TestColoursWithin(t *testing.T) {
+	s := CreateSyntaxFor(LanguageSyntax{StringChars: []string{"\""}, HlStrings: true})
+	s.c = DefaultColourScheme(COLOURS_256)
+	x := `a := "hello world" + b`
+
+	type testParam struct {
+		description    string
+		offset, max    uint
+		expectedText   string
+		expectedColour []Colour
+	}
+	str := s.c.Strings
+	tests := []testParam{
+		{"string opened off-screen", 8, 6, "llo wo", []Colour{str, str, str, str, str, str}},
+		{"string closed on-screen", 15, 5, `ld" +`, []Colour{str, str, str, "", ""}},
+		{"past end of row", 40, 5, "", []Colour{}},
+		{"truncated at end of row", 20, 5, " b", []Colour{"", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			l, hl := s.ColoursWithin(x, HlState{}, tt.offset, tt.max)
+			if l != tt.expectedText || !reflect.DeepEqual(hl, tt.expectedColour) {
+				t.Errorf("ColoursWithin(%d, %d) = %q, %q. Expected %q, %q", tt.offset, tt.max, l, hl, tt.expectedText, tt.expectedColour)
+			}
+		})
+	}
+
+	// Every viewport of the row shares one cache entry.
+	if hits, misses := s.cache.Stats(); misses != 1 || hits != 2 {
+		t.Errorf("Cache stats = (%d hits, %d misses), expected (2, 1)", hits, misses)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const MAX_PROMPT_HISTORY = 100
+
+// PromptHistory of previously submitted queries for a status bar prompt (i.e. search, replace). Persisted, one query
+// per line, to a file in the user's config directory.
+type PromptHistory struct {
+	entries []string
+	i       int // Index of currently recalled entry. len(entries) when not recalling.
+	file    string
+}
+
+// LoadPromptHistory with a given name from the user's config directory. An empty history is returned if no file exists.
+func LoadPromptHistory(name string) *PromptHistory {
+	h := &PromptHistory{entries: make([]string, 0)}
+
+	dir, err := ConfigDir()
+	if err != nil {
+		return h
+	}
+	h.file = filepath.Join(dir, name)
+
+	raw, err := os.ReadFile(h.file)
+	if err == nil {
+		for _, l := range strings.Split(string(raw), "\n") {
+			if len(l) > 0 {
+				h.entries = append(h.entries, l)
+			}
+		}
+	}
+	h.Reset()
+	return h
+}
+
+// Add a query to the end of history and persist it. Empty queries and repeats of the latest query are ignored.
+// Returns an error if the history couldn't be saved; the query is still recalled in this session.
+func (h *PromptHistory) Add(q string) error {
+	defer h.Reset()
+	if len(q) == 0 || strings.Contains(q, "\n") {
+		return nil
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == q {
+		return nil
+	}
+
+	h.entries = append(h.entries, q)
+	if len(h.entries) > MAX_PROMPT_HISTORY {
+		h.entries = h.entries[len(h.entries)-MAX_PROMPT_HISTORY:]
+	}
+	return h.Save()
+}
+
+// Prev returns the query before the currently recalled one. Stays on the oldest query once reached.
+func (h *PromptHistory) Prev() (string, bool) {
+	if len(h.entries) == 0 {
+		return "", false
+	}
+	if h.i > 0 {
+		h.i--
+	}
+	return h.entries[h.i], true
+}
+
+// Next returns the query after the currently recalled one. Returns false once past the latest query.
+func (h *PromptHistory) Next() (string, bool) {
+	if h.i+1 >= len(h.entries) {
+		h.i = len(h.entries)
+		return "", false
+	}
+	h.i++
+	return h.entries[h.i], true
+}
+
+// Reset recall position to after the latest query.
+func (h *PromptHistory) Reset() {
+	h.i = len(h.entries)
+}
+
+// Save history to its file, creating the config directory if needed.
+func (h *PromptHistory) Save() error {
+	if len(h.file) == 0 {
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(h.file), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(h.file, []byte(strings.Join(h.entries, "\n")+"\n"), 0644)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPromptHistory(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	h := LoadPromptHistory("search_history")
+	if _, exists := h.Prev(); exists {
+		t.Errorf("Expected empty history to have no previous entry")
+	}
+
+	h.Add("foo")
+	h.Add("bar")
+	h.Add("bar") // Repeats are ignored
+	h.Add("")
+
+	expected := []string{"bar", "foo", "foo"}
+	for _, x := range expected {
+		if q, _ := h.Prev(); q != x {
+			t.Errorf("Prev() = %s, want %s", q, x)
+		}
+	}
+	if q, exists := h.Next(); q != "bar" || !exists {
+		t.Errorf("Next() = %s, %t, want bar, true", q, exists)
+	}
+	if q, exists := h.Next(); q != "" || exists {
+		t.Errorf("Next() = %s, %t, want '', false", q, exists)
+	}
+
+	// History persists between sessions.
+	h2 := LoadPromptHistory("search_history")
+	if q, _ := h2.Prev(); q != "bar" {
+		t.Errorf("Prev() after reload = %s, want bar", q)
+	}
+}
+
+func TestPromptHistorySaveError(t *testing.T) {
+	notDir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notDir, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	h := &PromptHistory{file: filepath.Join(notDir, "search_history")}
+	if err := h.Add("foo"); err == nil {
+		t.Errorf("Expected Add() to return the error saving history")
+	}
+	if q, _ := h.Prev(); q != "foo" {
+		t.Errorf("Prev() = %s, want foo to be recalled despite the error", q)
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const KEYMAP_FILE = "keymap.json"
+
+// Action is the name of an editor command that keys can be bound to.
+type Action string
+
+const (
+	ACTION_SAVE          Action = "save"
+	ACTION_SAVE_AND_EXIT Action = "save-and-exit"
+	ACTION_EXIT          Action = "exit"
+	ACTION_SEARCH        Action = "search"
+	ACTION_GREP          Action = "grep"
+	ACTION_SET_LANGUAGE  Action = "set-language"
+	ACTION_MATCH_BRACKET Action = "match-bracket"
+	ACTION_UNDO          Action = "undo"
+	ACTION_COPY          Action = "copy"
+	ACTION_CUT           Action = "cut"
+	ACTION_PASTE         Action = "paste"
+	ACTION_YANK_POP      Action = "yank-pop"
+	ACTION_REGISTER      Action = "register"
+	ACTION_REGISTERS     Action = "registers"
+	ACTION_DELETE_ROW    Action = "delete-row"
+	ACTION_HELP          Action = "help"
+	ACTION_COMMAND       Action = "command"
+	ACTION_GOTO          Action = "goto"
+)
+
+// editorAction is an Action, and the command it runs.
+type editorAction struct {
+	name        Action
+	description string
+	run         func(e *Editor) bool // Returns true if the editor should exit.
+}
+
+// editorActions that can be bound to keys, in the order shown by the help screen.
+func editorActions() []editorAction {
+	return []editorAction{
+		{ACTION_SAVE, "Save the file", func(e *Editor) bool { return e.runSave(false) }},
+		{ACTION_SAVE_AND_EXIT, "Save the file and exit", func(e *Editor) bool { return e.runSave(true) }},
+		{ACTION_EXIT, "Exit without saving", func(e *Editor) bool { return true }},
+		{ACTION_SEARCH, "Search the file", func(e *Editor) bool { e.cx, e.cy = e.RunSearch(); return false }},
+		{ACTION_GREP, "Search files in the working directory", func(e *Editor) bool { e.RunGrep(); return false }},
+		{ACTION_SET_LANGUAGE, "Set the language to highlight", func(e *Editor) bool { e.RunSetLanguage(); return false }},
+		{ACTION_GOTO, "Go to a line and column", func(e *Editor) bool { e.RunGoto(); return false }},
+		{ACTION_MATCH_BRACKET, "Jump to the matching bracket", func(e *Editor) bool { e.JumpToMatchingBracket(); return false }},
+		{ACTION_UNDO, "Undo", func(e *Editor) bool { e.cmdHistory.Undo(e); return false }},
+		{ACTION_COPY, "Copy the selection, or start selecting", func(e *Editor) bool { e.Copy(); return false }},
+		{ACTION_CUT, "Cut the selection", func(e *Editor) bool { e.Cut(); return false }},
+		{ACTION_PASTE, "Paste at the cursor", func(e *Editor) bool { e.Paste(); return false }},
+		{ACTION_YANK_POP, "Replace the paste with an older cut", func(e *Editor) bool { e.YankPop(); return false }},
+		{ACTION_REGISTER, "Select a register for the next copy, cut or paste", func(e *Editor) bool { e.RunSelectRegister(); return false }},
+		{ACTION_REGISTERS, "List registers and cuts to paste", func(e *Editor) bool { e.RunRegisters(); return false }},
+		{ACTION_DELETE_ROW, "Cut the current row", func(e *Editor) bool { e.KillRow(); return false }},
+		{ACTION_HELP, "Show key bindings", func(e *Editor) bool { return e.RunHelp() }},
+		{ACTION_COMMAND, "Run a command, i.e. \"w\", \"goto 10\" or \"set number off\"", func(e *Editor) bool { return e.RunCommand() }},
+	}
+}
+
+// runSave of the file, reporting errors. Returns exit if the file was saved, so that a failed save doesn't exit.
+func (e *Editor) runSave(exit bool) bool {
+	if err := e.Save(); err != nil {
+		e.SetStatusMessage("Save error: %s", err)
+		return false
+	}
+	return exit
+}
+
+// findAction by name.
+func findAction(name Action) (editorAction, bool) {
+	for _, a := range editorActions() {
+		if a.name == name {
+			return a, true
+		}
+	}
+	return editorAction{}, false
+}
+
+// Keymap of keys, as decoded from input, to the action they run.
+type Keymap map[Cmd]Action
+
+// defaultBindings of key chords to actions, used unless overridden by keymap.json.
+var defaultBindings = map[string]Action{
+	"Ctrl-S": ACTION_SAVE,
+	"Ctrl-Q": ACTION_SAVE_AND_EXIT,
+	"Ctrl-W": ACTION_EXIT,
+	"Ctrl-F": ACTION_SEARCH,
+	"Ctrl-G": ACTION_GREP,
+	"Ctrl-L": ACTION_SET_LANGUAGE,
+	"Alt-g":  ACTION_GOTO,
+	"Ctrl-B": ACTION_MATCH_BRACKET,
+	"Ctrl-Z": ACTION_UNDO,
+	"Ctrl-C": ACTION_COPY,
+	"Ctrl-X": ACTION_CUT,
+	"Ctrl-V": ACTION_PASTE,
+	"Alt-y":  ACTION_YANK_POP,
+	"Ctrl-R": ACTION_REGISTER,
+	"Alt-r":  ACTION_REGISTERS,
+	"Ctrl-D": ACTION_DELETE_ROW,
+	"F1":     ACTION_HELP,
+	"Ctrl-E": ACTION_COMMAND,
+	"Alt-:":  ACTION_COMMAND,
+}
+
+// namedKeys that can be used in key chords, i.e. "Ctrl-Right".
+var namedKeys = map[string]Cmd{
+	"Up": UP, "Down": DOWN, "Left": LEFT, "Right": RIGHT, "Home": HOME_KEY, "End": END_KEY, "PageUp": PAGE_UP,
+	"PageDown": PAGE_DOWN, "Insert": INSERT, "Delete": DELETE, "Tab": TAB, "Enter": ENTER, "Escape": ESCAPE,
+	"Backspace": BACKSPACE, "F1": F1, "F2": F2, "F3": F3, "F4": F4, "F5": F5, "F6": F6, "F7": F7, "F8": F8, "F9": F9,
+	"F10": F10, "F11": F11, "F12": F12,
+}
+
+// reservedKeys edit text, move the cursor or select text, so can't be bound.
+var reservedKeys = []Cmd{
+	ENTER, ESCAPE, BACKSPACE, TAB, SHIFT_TAB, UP, DOWN, LEFT, RIGHT, HOME_KEY, END_KEY, PAGE_UP, PAGE_DOWN, DELETE,
+	CTRL_LEFT, CTRL_RIGHT,
+}
+
+// DefaultKeymap of gram's built in bindings.
+func DefaultKeymap() Keymap {
+	km, _ := ParseKeymap(defaultBindings)
+	return km
+}
+
+// LoadKeymap of the default bindings, with each of ConfigDirs' keymap.json merged over them, in order of precedence.
+// Errors from malformed files are returned alongside the keymap, and those files ignored.
+func LoadKeymap() (Keymap, []error) {
+	km := DefaultKeymap()
+	errs := make([]error, 0)
+
+	dirs := ConfigDirs()
+	for i := len(dirs) - 1; i >= 0; i-- {
+		overrides, err := LoadKeymapFromFile(filepath.Join(dirs[i], KEYMAP_FILE))
+		if isConfigError(err) {
+			errs = append(errs, err)
+		}
+		for k, a := range overrides {
+			if len(a) == 0 {
+				delete(km, k)
+			} else {
+				km[k] = a
+			}
+		}
+	}
+	return km, errs
+}
+
+// LoadKeymapFromFile of key chords to action names, i.e. {"Ctrl-S": "save", "Alt-x": "exit"}. An empty action unbinds
+// the key.
+func LoadKeymapFromFile(file string) (Keymap, error) {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return Keymap{}, err
+	}
+
+	var bindings map[string]Action
+	if err = json.Unmarshal(bytes, &bindings); err != nil {
+		return Keymap{}, fmt.Errorf("%s: %w", file, err)
+	}
+	km, err := ParseKeymap(bindings)
+	if err != nil {
+		return Keymap{}, fmt.Errorf("%s: %w", file, err)
+	}
+	return km, nil
+}
+
+// ParseKeymap from key chords to action names. Returns an error for unknown actions, invalid or reserved chords, and
+// chords for the same key bound to different actions.
+func ParseKeymap(bindings map[string]Action) (Keymap, error) {
+	chords := make([]string, 0, len(bindings))
+	for chord := range bindings {
+		chords = append(chords, chord)
+	}
+	sort.Strings(chords)
+
+	km := make(Keymap)
+	bound := make(map[Cmd]string)
+	for _, chord := range chords {
+		a := bindings[chord]
+		if _, exists := findAction(a); !exists && len(a) > 0 {
+			return Keymap{}, fmt.Errorf("%s: unknown action %q", chord, a)
+		}
+		k, err := ParseKeyChord(chord)
+		if err != nil {
+			return Keymap{}, err
+		}
+		if isReservedKey(k) {
+			return Keymap{}, fmt.Errorf("%s: key is reserved for editing", chord)
+		}
+		if other, exists := bound[k]; exists && km[k] != a {
+			return Keymap{}, fmt.Errorf("%s and %s are the same key, bound to %q and %q", other, chord, km[k], a)
+		}
+		km[k], bound[k] = a, chord
+	}
+	return km, nil
+}
+
+func isReservedKey(k Cmd) bool {
+	if _, ok := selectionMove(k); ok && k&MOD_SHIFT != 0 {
+		return true
+	}
+	for _, r := range reservedKeys {
+		if k == r {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseKeyChord of modifiers and a key, i.e. "Ctrl-S", "Alt-x", "Shift-F5" or "Ctrl-Alt-Right". Modifiers and named
+// keys are case insensitive. Keys without modifiers must be named keys.
+func ParseKeyChord(chord string) (Cmd, error) {
+	parts := strings.Split(chord, "-")
+	key := parts[len(parts)-1]
+	mods := parts[:len(parts)-1]
+	if len(key) == 0 && len(parts) > 1 {
+		key, mods = "-", parts[:len(parts)-2] // i.e. "Alt--"
+	}
+
+	var m Cmd
+	ctrl := false
+	for _, mod := range mods {
+		switch strings.ToLower(mod) {
+		case "ctrl", "c":
+			ctrl = true
+		case "alt", "meta", "m", "a":
+			m |= MOD_ALT
+		case "shift", "s":
+			m |= MOD_SHIFT
+		default:
+			return 0, fmt.Errorf("%s: unknown modifier %q", chord, mod)
+		}
+	}
+
+	for name, k := range namedKeys {
+		if strings.EqualFold(name, key) {
+			if ctrl {
+				m |= MOD_CTRL
+			}
+			return k | m, nil
+		}
+	}
+
+	if len(key) != 1 || isControlChar(key[0]) || key[0] >= 0x80 {
+		return 0, fmt.Errorf("%s: unknown key %q", chord, key)
+	}
+	c := key[0]
+	if ctrl {
+		// Terminals send Ctrl with a letter or one of @[\]^_ as a control character, without Shift or Alt.
+		upper := strings.ToUpper(key)[0]
+		if upper < '@' || upper > '_' || m != 0 {
+			return 0, fmt.Errorf("%s: terminals can't send Ctrl with %q", chord, key)
+		}
+		return Cmd(upper&0x1f) | m, nil
+	}
+	if m&MOD_ALT == 0 || m&MOD_SHIFT != 0 {
+		return 0, fmt.Errorf("%s: characters can only be bound with Ctrl or Alt", chord)
+	}
+	return Cmd(c) | m, nil
+}
+
+// KeyChordName of a key, as parsed by ParseKeyChord.
+func KeyChordName(k Cmd) string {
+	prefix := ""
+	if k&MOD_CTRL != 0 {
+		prefix += "Ctrl-"
+	}
+	if k&MOD_ALT != 0 {
+		prefix += "Alt-"
+	}
+	if k&MOD_SHIFT != 0 {
+		prefix += "Shift-"
+	}
+	base := k &^ (MOD_CTRL | MOD_ALT | MOD_SHIFT)
+
+	for name, c := range namedKeys {
+		if c == base {
+			return prefix + name
+		}
+	}
+	if base < 32 {
+		return prefix + "Ctrl-" + string(rune(base+'@'))
+	}
+	return prefix + string(rune(base))
+}
+
+// Bindings of an action in the keymap, as sorted key chord names.
+func (km Keymap) Bindings(a Action) []string {
+	chords := make([]string, 0)
+	for k, x := range km {
+		if x == a {
+			chords = append(chords, KeyChordName(k))
+		}
+	}
+	sort.Strings(chords)
+	return chords
+}
+
+// RunAction bound to key k, if any. Returns whether k is bound, and whether the editor should exit.
+func (e *Editor) RunAction(k Cmd) (bool, bool) {
+	a, exists := findAction(e.keymap[k])
+	if !exists {
+		return false, false
+	}
+	return true, a.run(e)
+}
+
+// RunHelp lists every action and the keys bound to it. The selected action is run. Returns true if the editor should
+// exit.
+func (e *Editor) RunHelp() bool {
+	actions := editorActions()
+	items := make([]string, len(actions))
+	for i, a := range actions {
+		keys := strings.Join(e.keymap.Bindings(a.name), ", ")
+		if len(keys) == 0 {
+			keys = "(unbound)"
+		}
+		items[i] = fmt.Sprintf("%-16s %-16s %s", keys, a.name, a.description)
+	}
+
+	i, ok := e.RunList("KEYS (ENTER to run)", items)
+	if !ok {
+		return false
+	}
+	return actions[i].run(e)
+}
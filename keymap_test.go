@@ -0,0 +1,143 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseKeyChord(t *testing.T) {
+	type testParam struct {
+		chord    string
+		expected Cmd
+		valid    bool
+	}
+	tests := []testParam{
+		{"Ctrl-S", 19, true},
+		{"ctrl-s", 19, true},
+		{"C-s", 19, true},
+		{"Ctrl-_", 31, true},
+		{"Alt-x", 'x' | MOD_ALT, true},
+		{"Alt-X", 'X' | MOD_ALT, true},
+		{"Alt--", '-' | MOD_ALT, true},
+		{"F1", F1, true},
+		{"Shift-F5", F5 | MOD_SHIFT, true},
+		{"Ctrl-Alt-Right", RIGHT | MOD_CTRL | MOD_ALT, true},
+		{"ctrl-pageup", PAGE_UP | MOD_CTRL, true},
+		{"x", 0, false},
+		{"Shift-x", 0, false},
+		{"Ctrl-Shift-S", 0, false},
+		{"Ctrl-Alt-S", 0, false},
+		{"Ctrl-1", 0, false},
+		{"Hyper-x", 0, false},
+		{"Ctrl-Foo", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chord, func(t *testing.T) {
+			k, err := ParseKeyChord(tt.chord)
+			if (err == nil) != tt.valid || k != tt.expected {
+				t.Errorf("ParseKeyChord(%q) = %d, %v. Expected %d, valid: %t", tt.chord, k, err, tt.expected, tt.valid)
+			}
+			if err == nil {
+				if again, _ := ParseKeyChord(KeyChordName(k)); again != k {
+					t.Errorf("KeyChordName(%d) = %q doesn't parse to the same key", k, KeyChordName(k))
+				}
+			}
+		})
+	}
+}
+
+func TestParseKeymap(t *testing.T) {
+	type testParam struct {
+		description string
+		bindings    map[string]Action
+		valid       bool
+	}
+	tests := []testParam{
+		{"valid", map[string]Action{"Ctrl-S": ACTION_SAVE, "Alt-s": ACTION_SAVE, "F2": ""}, true},
+		{"same key, same action", map[string]Action{"Ctrl-S": ACTION_SAVE, "ctrl-s": ACTION_SAVE}, true},
+		{"conflict", map[string]Action{"Ctrl-S": ACTION_SAVE, "ctrl-s": ACTION_EXIT}, false},
+		{"unknown action", map[string]Action{"Ctrl-S": "fly"}, false},
+		{"invalid chord", map[string]Action{"Ctrl-": ACTION_SAVE}, false},
+		{"reserved key", map[string]Action{"Ctrl-M": ACTION_SAVE}, false},
+		{"reserved movement", map[string]Action{"Ctrl-Right": ACTION_SAVE}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			if _, err := ParseKeymap(tt.bindings); (err == nil) != tt.valid {
+				t.Errorf("ParseKeymap(%v) error = %v, expected valid: %t", tt.bindings, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestDefaultKeymap(t *testing.T) {
+	if _, err := ParseKeymap(defaultBindings); err != nil {
+		t.Fatalf("Invalid default bindings: %v", err)
+	}
+	km := DefaultKeymap()
+	for _, a := range editorActions() {
+		if len(km.Bindings(a.name)) == 0 {
+			t.Errorf("Expected a default binding for %s", a.name)
+		}
+	}
+}
+
+func TestLoadKeymap(t *testing.T) {
+	user, _, system := setConfigDirs(t)
+	writeConfigFile(t, system, KEYMAP_FILE, `{"Ctrl-E": "exit", "Ctrl-R": "search"}`)
+	writeConfigFile(t, user, KEYMAP_FILE, `{"Ctrl-R": "grep", "Ctrl-W": ""}`)
+
+	km, errs := LoadKeymap()
+	if len(errs) != 0 {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+	if b := km.Bindings(ACTION_EXIT); !reflect.DeepEqual(b, []string{"Ctrl-E"}) {
+		t.Errorf("Bindings(exit) = %v, expected [Ctrl-E]", b)
+	}
+	if b := km.Bindings(ACTION_GREP); !reflect.DeepEqual(b, []string{"Ctrl-G", "Ctrl-R"}) {
+		t.Errorf("Bindings(grep) = %v, expected user binding to take precedence", b)
+	}
+	if b := km.Bindings(ACTION_SAVE); !reflect.DeepEqual(b, []string{"Ctrl-S"}) {
+		t.Errorf("Bindings(save) = %v, expected default binding", b)
+	}
+
+	writeConfigFile(t, user, KEYMAP_FILE, `{"Ctrl-R": "grep", "ctrl-r": "save"}`)
+	km, errs = LoadKeymap()
+	if len(errs) != 1 {
+		t.Errorf("Expected an error for conflicting bindings, got %v", errs)
+	}
+	if km[18] != ACTION_SEARCH {
+		t.Errorf("Expected invalid keymap to be ignored, Ctrl-R is %q", km[18])
+	}
+}
+
+func TestSaveActions(t *testing.T) {
+	type testParam struct {
+		description string
+		action      Action
+		missingDir  bool
+		exit        bool
+	}
+	tests := []testParam{
+		{"save", ACTION_SAVE, false, false},
+		{"save and exit", ACTION_SAVE_AND_EXIT, false, true},
+		{"failed save", ACTION_SAVE, true, false},
+		{"failed save doesn't exit", ACTION_SAVE_AND_EXIT, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			e := testEditor("x")
+			e.filename = filepath.Join(t.TempDir(), "a.txt")
+			if tt.missingDir {
+				e.filename = filepath.Join(t.TempDir(), "missing", "a.txt")
+			}
+			e.Edited(0)
+			a, _ := findAction(tt.action)
+			if exit := a.run(e); exit != tt.exit || e.dirty != tt.missingDir || (len(e.statusMsg) > 0) != tt.missingDir {
+				t.Errorf("%s = %t, dirty: %t, status %q. Expected %t", tt.action, exit, e.dirty, e.statusMsg, tt.exit)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Maximum length of the parameters of an escape sequence. Longer sequences are read to their end, and discarded.
+const MAX_ESCAPE_LEN = 32
+
+// Modifiers of a key, added to its Cmd.
+const (
+	MOD_SHIFT Cmd = 1 << 12
+	MOD_ALT   Cmd = 1 << 13
+	MOD_CTRL  Cmd = 1 << 14
+)
+
+// csiKeys are keys identified by the final byte of a CSI (ESC [) sequence, i.e. ESC [ A, or ESC [ 1 ; 5 A.
+var csiKeys = map[byte]Cmd{
+	'A': UP, 'B': DOWN, 'C': RIGHT, 'D': LEFT, 'F': END_KEY, 'H': HOME_KEY, 'Z': TAB | MOD_SHIFT,
+	'P': F1, 'Q': F2, 'R': F3, 'S': F4,
+}
+
+// ss3Keys are keys identified by the final byte of an SS3 (ESC O) sequence, sent in application cursor mode.
+var ss3Keys = map[byte]Cmd{
+	'A': UP, 'B': DOWN, 'C': RIGHT, 'D': LEFT, 'F': END_KEY, 'H': HOME_KEY,
+	'P': F1, 'Q': F2, 'R': F3, 'S': F4,
+}
+
+// tildeKeys are keys identified by the first parameter of a CSI sequence ending in '~', i.e. ESC [ 3 ~.
+var tildeKeys = map[int]Cmd{
+	1: HOME_KEY, 2: INSERT, 3: DELETE, 4: END_KEY, 5: PAGE_UP, 6: PAGE_DOWN, 7: HOME_KEY, 8: END_KEY,
+	11: F1, 12: F2, 13: F3, 14: F4, 15: F5, 17: F6, 18: F7, 19: F8, 20: F9, 21: F10, 23: F11, 24: F12,
+	200: PASTE_START, 201: PASTE_END,
+}
+
+// DecodeEscape decodes the key of an escape sequence, after its initial ESC has been read. Bytes are read with next,
+// which returns false if no byte arrives before the read timeout. A bare ESC (no byte follows before the timeout),
+// and unrecognised sequences, are ESCAPE. ESC followed by a printable character is that character with MOD_ALT.
+func DecodeEscape(next func() (byte, bool)) Cmd {
+	c, _ := DecodeEvent(next)
+	return c
+}
+
+// DecodeEvent is DecodeEscape, also returning the event of MOUSE sequences.
+func DecodeEvent(next func() (byte, bool)) (Cmd, MouseEvent) {
+	a, ok := next()
+	if !ok || a == ESCAPE {
+		return ESCAPE, MouseEvent{}
+	}
+
+	switch a {
+	case '[':
+		return decodeSequence(next, a, csiKeys)
+	case 'O':
+		return decodeSequence(next, a, ss3Keys)
+	}
+	if !isControlChar(a) {
+		return Cmd(a) | MOD_ALT, MouseEvent{}
+	}
+	return ESCAPE, MouseEvent{}
+}
+
+// decodeSequence of parameters, terminated by a final byte, after the introducer (i.e. '[') has been read. Parameters
+// are ';' separated: the key (for '~' sequences), then xterm's modifier (1 + Shift + 2*Alt + 4*Ctrl + 8*Meta). SGR
+// mouse sequences (ESC [ < ...) are decoded as MOUSE.
+func decodeSequence(next func() (byte, bool), introducer byte, keys map[byte]Cmd) (Cmd, MouseEvent) {
+	params := make([]byte, 0)
+	var final byte
+	overlong := false
+	for {
+		c, ok := next()
+		if !ok {
+			if len(params) == 0 {
+				return Cmd(introducer) | MOD_ALT, MouseEvent{} // ESC [ typed as Alt-[
+			}
+			return ESCAPE, MouseEvent{}
+		}
+		if c >= 0x40 && c <= 0x7E {
+			final = c
+			break
+		}
+		if c == ESCAPE {
+			return ESCAPE, MouseEvent{}
+		}
+		if len(params) < MAX_ESCAPE_LEN {
+			params = append(params, c)
+		} else {
+			overlong = true // Read to the end of the sequence, so its remainder isn't read as keys
+		}
+	}
+	if overlong {
+		return ESCAPE, MouseEvent{}
+	}
+
+	if introducer == '[' && len(params) > 0 && params[0] == '<' {
+		m, ok := decodeMouse(string(params[1:]), final)
+		if !ok {
+			return ESCAPE, MouseEvent{}
+		}
+		return MOUSE, m
+	}
+
+	fields := strings.Split(string(params), ";")
+	var key Cmd
+	var exists bool
+	if final == '~' && introducer == '[' {
+		n, _ := strconv.Atoi(fields[0])
+		key, exists = tildeKeys[n]
+	} else {
+		key, exists = keys[final]
+	}
+	if !exists {
+		return ESCAPE, MouseEvent{}
+	}
+
+	if len(fields) > 1 {
+		m, err := strconv.Atoi(fields[1])
+		if err != nil || m < 1 {
+			return ESCAPE, MouseEvent{}
+		}
+		key |= modifiers(m - 1)
+	}
+	return key, MouseEvent{}
+}
+
+// modifiers from the bits of xterm's modifier parameter. Meta is treated as Alt.
+func modifiers(bits int) Cmd {
+	var m Cmd
+	if bits&1 != 0 {
+		m |= MOD_SHIFT
+	}
+	if bits&(2|8) != 0 {
+		m |= MOD_ALT
+	}
+	if bits&4 != 0 {
+		m |= MOD_CTRL
+	}
+	return m
+}
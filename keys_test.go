@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+)
+
+// byteStream returns bytes of s in order, then times out.
+func byteStream(s string) func() (byte, bool) {
+	i := 0
+	return func() (byte, bool) {
+		if i >= len(s) {
+			return 0, false
+		}
+		i++
+		return s[i-1], true
+	}
+}
+
+func TestDecodeEscape(t *testing.T) {
+	type testParam struct {
+		description string
+		input       string // After the initial ESC
+		expected    Cmd
+	}
+	tests := []testParam{
+		{"bare escape", "", ESCAPE},
+		{"double escape", "\x1b", ESCAPE},
+		{"CSI arrow", "[A", UP},
+		{"SS3 arrow", "OD", LEFT},
+		{"CSI home", "[H", HOME_KEY},
+		{"SS3 end", "OF", END_KEY},
+		{"tilde home", "[1~", HOME_KEY},
+		{"tilde insert", "[2~", INSERT},
+		{"tilde delete", "[3~", DELETE},
+		{"tilde end", "[4~", END_KEY},
+		{"page up", "[5~", PAGE_UP},
+		{"page down", "[6~", PAGE_DOWN},
+		{"F1 SS3", "OP", F1},
+		{"F4 SS3", "OS", F4},
+		{"F5", "[15~", F5},
+		{"F6", "[17~", F6},
+		{"F10", "[21~", F10},
+		{"F12", "[24~", F12},
+		{"paste start", "[200~", PASTE_START},
+		{"paste end", "[201~", PASTE_END},
+		{"shift right", "[1;2C", SHIFT_RIGHT},
+		{"shift left", "[1;2D", SHIFT_LEFT},
+		{"ctrl right", "[1;5C", CTRL_RIGHT},
+		{"ctrl left", "[1;5D", CTRL_LEFT},
+		{"alt up", "[1;3A", UP | MOD_ALT},
+		{"ctrl shift down", "[1;6B", DOWN | MOD_CTRL | MOD_SHIFT},
+		{"meta as alt", "[1;9A", UP | MOD_ALT},
+		{"ctrl home", "[1;5H", HOME_KEY | MOD_CTRL},
+		{"shift delete", "[3;2~", DELETE | MOD_SHIFT},
+		{"ctrl F5", "[15;5~", F5 | MOD_CTRL},
+		{"shift F1", "[1;2P", F1 | MOD_SHIFT},
+		{"shift tab", "[Z", TAB | MOD_SHIFT},
+		{"alt letter", "x", 'x' | MOD_ALT},
+		{"alt [", "[", '[' | MOD_ALT},
+		{"alt O", "O", 'O' | MOD_ALT},
+		{"unknown final", "[1;5X", ESCAPE},
+		{"unknown tilde", "[99~", ESCAPE},
+		{"invalid modifier", "[1;xA", ESCAPE},
+		{"truncated", "[1;5", ESCAPE},
+		{"escape within sequence", "[1\x1b", ESCAPE},
+		{"control character", "\x01", ESCAPE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			if c := DecodeEscape(byteStream(tt.input)); c != tt.expected {
+				t.Errorf("DecodeEscape(%q) = %d, expected %d", tt.input, c, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDecodeEscapeConsumesSequence(t *testing.T) {
+	next := byteStream("[1;5Cx")
+	if c := DecodeEscape(next); c != CTRL_RIGHT {
+		t.Fatalf("DecodeEscape() = %d, expected %d", c, CTRL_RIGHT)
+	}
+	if b, ok := next(); !ok || b != 'x' {
+		t.Errorf("Expected bytes after the sequence to remain, got %q", b)
+	}
+}
+
+func TestDecodeEscapeBoundsLength(t *testing.T) {
+	long := "["
+	for i := 0; i < 2*MAX_ESCAPE_LEN; i++ {
+		long += "1"
+	}
+	next := byteStream(long + "~x")
+	if c := DecodeEscape(next); c != ESCAPE {
+		t.Errorf("Expected overlong sequence to be discarded, got %d", c)
+	}
+	if b, ok := next(); !ok || b != 'x' {
+		t.Errorf("Expected the overlong sequence to be read to its end, then %q, got %q", 'x', b)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+)
+
+// RunList displays items full screen, below a title, for the user to navigate with UP/DOWN/PAGE_UP/PAGE_DOWN.
+// Returns the index of the item selected with ENTER, or false if the list was left with ESC (or is empty).
+func (e *Editor) RunList(title string, items []string) (int, bool) {
+	if len(items) == 0 {
+		return 0, false
+	}
+	selected, offset := 0, 0
+	height := int(e.GetEditorRows())
+
+	for {
+		if selected < offset {
+			offset = selected
+		} else if selected >= offset+height {
+			offset = selected - height + 1
+		}
+		e.DrawList(title, items, selected, offset)
+
+		b := e.ReadChar()
+		switch b {
+		case ENTER:
+			return selected, true
+		case '\x1b':
+			switch e.HandleEscapeCode() {
+			case '\x1b':
+				return 0, false
+			case UP:
+				selected--
+			case DOWN:
+				selected++
+			case PAGE_UP:
+				selected -= height
+			case PAGE_DOWN:
+				selected += height
+			case HOME_KEY:
+				selected = 0
+			case END_KEY:
+				selected = len(items) - 1
+			}
+		}
+		if selected < 0 {
+			selected = 0
+		} else if selected >= len(items) {
+			selected = len(items) - 1
+		}
+	}
+}
+
+// DrawList of items from offset, with the selected item highlighted as a selection.
+func (e *Editor) DrawList(title string, items []string, selected, offset int) {
+	fmt.Printf("\x1b[2J")
+	fmt.Printf("\x1b[H")
+
+	end := offset + int(e.GetEditorRows())
+	if end > len(items) {
+		end = len(items)
+	}
+	for i := offset; i < end; i++ {
+		l := ConstructRow(items[i]).RenderWithin(0, e.wCols)
+		if i == selected {
+			fmt.Printf("%s\r\n", C(l, e.colours.Selection))
+		} else {
+			fmt.Printf("%s\r\n", l)
+		}
+	}
+
+	e.MoveCursorToStatusBar()
+	e.ClearLine()
+	fmt.Printf("%s (%d/%d)", title, selected+1, len(items))
+}
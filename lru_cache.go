@@ -1,88 +1,115 @@
 package main
 
-// LRUCache for string->string
-// TODO: Currently WIP, its just a map.
-type LRUCache struct {
-	m map[string]LinkedNode
+// LRUCache of at most n entries. Setting a new entry in a full cache evicts the least recently used entry. Get and Set
+// are O(1).
+type LRUCache[K comparable, V any] struct {
+	m map[K]*LinkedNode[K, V]
 	n int
 
-	lru *LinkedNode
-	mru *LinkedNode
-}
+	lru *LinkedNode[K, V]
+	mru *LinkedNode[K, V]
 
-func CreateCache(capacity int) *LRUCache {
-	return &LRUCache{
-		m: make(map[string]LinkedNode, capacity),
-		n: capacity,
-	}
+	hits, misses int
 }
 
-func (l *LRUCache) Get(k string) (string, bool) {
-	v, exists := l.pop(k)
-	if !exists {
-		return "", false
+func CreateCache[K comparable, V any](capacity int) *LRUCache[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRUCache[K, V]{
+		m: make(map[K]*LinkedNode[K, V], capacity),
+		n: capacity,
 	}
-
-	l.Set(k, v)
-
-	return v, exists
 }
 
-func (l *LRUCache) pop(k string) (string, bool) {
+// Get the value of k, marking it as the most recently used entry.
+func (l *LRUCache[K, V]) Get(k K) (V, bool) {
 	node, exists := l.m[k]
 	if !exists {
-		return "", false
+		l.misses++
+		var v V
+		return v, false
 	}
+	l.hits++
 
-	node.Remove()
-
+	l.unlink(node)
+	l.pushMru(node)
 	return node.v, true
 }
 
-func (l *LRUCache) Set(k, v string) {
-	if len(l.m)+1 > l.n {
+// Set the value of k, as the most recently used entry.
+func (l *LRUCache[K, V]) Set(k K, v V) {
+	if node, exists := l.m[k]; exists {
+		node.v = v
+		l.unlink(node)
+		l.pushMru(node)
+		return
+	}
+
+	if len(l.m) >= l.n {
 		l.evictLRU()
 	}
+	node := &LinkedNode[K, V]{k: k, v: v}
+	l.pushMru(node)
+	l.m[k] = node
+}
 
-	l.setNewMru(k, v)
-	l.m[k] = *l.mru
+// Len is the number of entries in the cache.
+func (l *LRUCache[K, V]) Len() int {
+	return len(l.m)
 }
 
-func (l *LRUCache) setNewMru(k, v string) {
-	n := &LinkedNode{
-		k: k,
-		v: v,
-	}
-	if l.mru != nil {
-		currMru := l.mru
-		n.prev = currMru
-		currMru.next = n
+// Stats of cache hits and misses from Get, shown in the status bar for the highlighting cache.
+func (l *LRUCache[K, V]) Stats() (int, int) {
+	return l.hits, l.misses
+}
 
+func (l *LRUCache[K, V]) pushMru(n *LinkedNode[K, V]) {
+	n.prev = l.mru
+	n.next = nil
+	if l.mru != nil {
+		l.mru.next = n
 	}
 	l.mru = n
+	if l.lru == nil {
+		l.lru = n
+	}
+}
+
+// unlink n from the list, keeping the cache's ends valid.
+func (l *LRUCache[K, V]) unlink(n *LinkedNode[K, V]) {
+	if l.lru == n {
+		l.lru = n.next
+	}
+	if l.mru == n {
+		l.mru = n.prev
+	}
+	n.Remove()
 }
 
-func (l *LRUCache) evictLRU() {
+func (l *LRUCache[K, V]) evictLRU() {
 	currLru := l.lru
 	if currLru != nil {
-		l.lru = currLru.next
+		l.unlink(currLru)
 		delete(l.m, currLru.k)
 	}
 }
 
 // LinkedNode structure for the basis of a doubly-linked list.
-type LinkedNode struct {
-	prev *LinkedNode
-	next *LinkedNode
-	k, v string
+type LinkedNode[K comparable, V any] struct {
+	prev *LinkedNode[K, V]
+	next *LinkedNode[K, V]
+	k    K
+	v    V
 }
 
 // Remove a LinkedNode from its neighbours.
-func (n *LinkedNode) Remove() {
+func (n *LinkedNode[K, V]) Remove() {
 	if n.prev != nil {
 		n.prev.next = n.next
 	}
 	if n.next != nil {
 		n.next.prev = n.prev
 	}
+	n.prev, n.next = nil, nil
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestLRUCache(t *testing.T) {
+	type testParam struct {
+		description string
+		capacity    int
+		ops         func(c *LRUCache[string, int])
+		present     []string
+		absent      []string
+	}
+	tests := []testParam{{
+		description: "Entries within capacity are kept",
+		capacity:    3,
+		ops: func(c *LRUCache[string, int]) {
+			c.Set("a", 1)
+			c.Set("b", 2)
+			c.Set("c", 3)
+		},
+		present: []string{"a", "b", "c"},
+	}, {
+		description: "Least recently set entry is evicted",
+		capacity:    2,
+		ops: func(c *LRUCache[string, int]) {
+			c.Set("a", 1)
+			c.Set("b", 2)
+			c.Set("c", 3)
+		},
+		present: []string{"b", "c"},
+		absent:  []string{"a"},
+	}, {
+		description: "Get marks an entry as recently used",
+		capacity:    2,
+		ops: func(c *LRUCache[string, int]) {
+			c.Set("a", 1)
+			c.Set("b", 2)
+			c.Get("a")
+			c.Set("c", 3)
+		},
+		present: []string{"a", "c"},
+		absent:  []string{"b"},
+	}, {
+		description: "Overwriting an entry doesn't evict",
+		capacity:    2,
+		ops: func(c *LRUCache[string, int]) {
+			c.Set("a", 1)
+			c.Set("b", 2)
+			c.Set("a", 3)
+			c.Set("a", 4)
+		},
+		present: []string{"a", "b"},
+	}, {
+		description: "Capacity of one",
+		capacity:    1,
+		ops: func(c *LRUCache[string, int]) {
+			c.Set("a", 1)
+			c.Get("a")
+			c.Set("b", 2)
+		},
+		present: []string{"b"},
+		absent:  []string{"a"},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c := CreateCache[string, int](tt.capacity)
+			tt.ops(c)
+			for _, k := range tt.present {
+				if _, exists := c.Get(k); !exists {
+					t.Errorf("Expected %s in cache", k)
+				}
+			}
+			for _, k := range tt.absent {
+				if _, exists := c.Get(k); exists {
+					t.Errorf("Expected %s to be evicted", k)
+				}
+			}
+			if c.Len() > tt.capacity {
+				t.Errorf("Len() = %d, exceeds capacity %d", c.Len(), tt.capacity)
+			}
+		})
+	}
+}
+
+func TestLRUCacheValues(t *testing.T) {
+	c := CreateCache[int, string](2)
+	c.Set(1, "a")
+	c.Set(1, "b")
+	if v, _ := c.Get(1); v != "b" {
+		t.Errorf("Get(1) = %s, expected b", v)
+	}
+}
+
+func TestLRUCacheStats(t *testing.T) {
+	c := CreateCache[string, string](2)
+	c.Set("a", "1")
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	if hits, misses := c.Stats(); hits != 2 || misses != 1 {
+		t.Errorf("Stats() = (%d, %d), expected (2, 1)", hits, misses)
+	}
+}
+
+func TestLRUCacheBounded(t *testing.T) {
+	c := CreateCache[int, int](100)
+	for i := 0; i < 10000; i++ {
+		c.Set(i, i)
+		if i%3 == 0 {
+			c.Get(i / 2)
+		}
+	}
+	if c.Len() != 100 {
+		t.Errorf("Len() = %d, expected 100", c.Len())
+	}
+
+	// Walking the list from either end visits every entry exactly once.
+	n := 0
+	for node := c.lru; node != nil; node = node.next {
+		n++
+	}
+	m := 0
+	for node := c.mru; node != nil; node = node.prev {
+		m++
+	}
+	if n != 100 || m != 100 {
+		t.Errorf("Expected 100 linked entries, found %d from LRU and %d from MRU", n, m)
+	}
+	for i := 9900; i < 10000; i++ {
+		if _, exists := c.Get(i); !exists {
+			t.Errorf("Expected recent entry %d in cache", i)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Escape sequences enabling and disabling mouse reporting of clicks and drags (1000, 1002), with SGR encoding (1006).
+const (
+	MOUSE_ON  = "\x1b[?1000h\x1b[?1002h\x1b[?1006h"
+	MOUSE_OFF = "\x1b[?1006l\x1b[?1002l\x1b[?1000l"
+)
+
+// MOUSE is the key decoded from an SGR mouse sequence. See MouseEvent.
+const MOUSE Cmd = 1024
+
+// Number of rows scrolled by each step of the mouse wheel.
+const MOUSE_SCROLL_ROWS = 3
+
+// Buttons of a MouseEvent.
+const (
+	MOUSE_LEFT       = 0
+	MOUSE_MIDDLE     = 1
+	MOUSE_RIGHT      = 2
+	MOUSE_WHEEL_UP   = 64
+	MOUSE_WHEEL_DOWN = 65
+)
+
+// MouseEvent of a press, drag or release of a mouse button, in screen coordinates from (0, 0).
+type MouseEvent struct {
+	Button  int
+	X, Y    uint
+	Drag    bool // Moved while pressed
+	Release bool
+	Mods    Cmd // MOD_SHIFT, MOD_ALT and MOD_CTRL
+}
+
+// decodeMouse from the parameters of an SGR mouse sequence (ESC [ < button ; x ; y M), after the '<'. Final is 'M' for
+// presses and drags, and 'm' for releases.
+func decodeMouse(params string, final byte) (MouseEvent, bool) {
+	fields := strings.Split(params, ";")
+	if len(fields) != 3 || (final != 'M' && final != 'm') {
+		return MouseEvent{}, false
+	}
+	n := make([]int, 3)
+	for i, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil || v < 0 || (i > 0 && v < 1) {
+			return MouseEvent{}, false
+		}
+		n[i] = v
+	}
+
+	b := n[0]
+	m := MouseEvent{Button: b &^ (4 | 8 | 16 | 32), X: uint(n[1] - 1), Y: uint(n[2] - 1), Drag: b&32 != 0, Release: final == 'm'}
+	if b&4 != 0 {
+		m.Mods |= MOD_SHIFT
+	}
+	if b&8 != 0 {
+		m.Mods |= MOD_ALT
+	}
+	if b&16 != 0 {
+		m.Mods |= MOD_CTRL
+	}
+	return m, true
+}
+
+// HandleMouse event: left click moves the cursor, dragging selects from the click to the cursor, and the wheel scrolls.
+func (e *Editor) HandleMouse(m MouseEvent) {
+	switch {
+	case m.Button == MOUSE_WHEEL_UP:
+		e.Scroll(-MOUSE_SCROLL_ROWS)
+	case m.Button == MOUSE_WHEEL_DOWN:
+		e.Scroll(MOUSE_SCROLL_ROWS)
+	case m.Button != MOUSE_LEFT:
+	case m.Release:
+		e.mouseDown = false
+	case m.Drag:
+		if e.mouseDown {
+			e.cx, e.cy = e.ScreenToDocument(m.X, m.Y)
+			e.selecting = e.cx != e.ax || e.cy != e.ay
+		}
+	case m.Y < e.GetEditorRows():
+		e.cx, e.cy = e.ScreenToDocument(m.X, m.Y)
+		e.ax, e.ay = e.cx, e.cy
+		e.ClearSelection()
+		e.mouseDown = true
+	}
+}
+
+// ScreenToDocument coordinates, from screen coordinates (x, y) within the rows of the editor. Coordinates beyond the
+// end of a row, or of the file, are moved to its end.
+func (e *Editor) ScreenToDocument(x, y uint) (uint, uint) {
+	if e.GetDocumentRows() == 0 {
+		return 0, 0
+	}
+	if y >= e.GetEditorRows() {
+		y = e.GetEditorRows() - 1
+	}
+	dy := y + e.rowOffset
+	if dy >= e.GetDocumentRows() {
+		dy = e.GetDocumentRows() - 1
+	}
+
+	dx := e.colOffset
+	if x > e.GutterWidth() {
+		dx += x - e.GutterWidth()
+	}
+	return e.GetRow(dy).snapRenderIndex(dx), dy
+}
+
+// Scroll the view by n rows (up if negative), without moving the cursor unless it would leave the view.
+func (e *Editor) Scroll(n int) {
+	offset := int(e.rowOffset) + n
+	if max := int(e.GetDocumentRows()) - 1; offset > max {
+		offset = max
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	e.rowOffset = uint(offset)
+
+	if e.cy < e.rowOffset {
+		e.cy = e.rowOffset
+	} else if e.cy >= e.rowOffset+e.GetEditorRows() {
+		e.cy = e.rowOffset + e.GetEditorRows() - 1
+	}
+	if l := e.GetRowLength(); e.cx > l {
+		e.cx = l
+	}
+}
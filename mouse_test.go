@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDecodeMouse(t *testing.T) {
+	type testParam struct {
+		description string
+		input       string // After the initial ESC
+		expected    Cmd
+		event       MouseEvent
+	}
+	tests := []testParam{
+		{"left press", "[<0;5;3M", MOUSE, MouseEvent{Button: MOUSE_LEFT, X: 4, Y: 2}},
+		{"left release", "[<0;5;3m", MOUSE, MouseEvent{Button: MOUSE_LEFT, X: 4, Y: 2, Release: true}},
+		{"left drag", "[<32;10;1M", MOUSE, MouseEvent{Button: MOUSE_LEFT, X: 9, Drag: true}},
+		{"right press", "[<2;1;1M", MOUSE, MouseEvent{Button: MOUSE_RIGHT}},
+		{"wheel up", "[<64;1;1M", MOUSE, MouseEvent{Button: MOUSE_WHEEL_UP}},
+		{"wheel down", "[<65;1;1M", MOUSE, MouseEvent{Button: MOUSE_WHEEL_DOWN}},
+		{"shift ctrl click", "[<20;1;1M", MOUSE, MouseEvent{Button: MOUSE_LEFT, Mods: MOD_SHIFT | MOD_CTRL}},
+		{"alt click", "[<8;1;1M", MOUSE, MouseEvent{Button: MOUSE_LEFT, Mods: MOD_ALT}},
+		{"missing field", "[<0;1M", ESCAPE, MouseEvent{}},
+		{"zero coordinate", "[<0;0;1M", ESCAPE, MouseEvent{}},
+		{"not a number", "[<0;x;1M", ESCAPE, MouseEvent{}},
+		{"wrong final byte", "[<0;1;1A", ESCAPE, MouseEvent{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c, m := DecodeEvent(byteStream(tt.input))
+			if c != tt.expected || m != tt.event {
+				t.Errorf("DecodeEvent(%q) = %d, %+v, expected %d, %+v", tt.input, c, m, tt.expected, tt.event)
+			}
+		})
+	}
+}
+
+func TestScreenToDocument(t *testing.T) {
+	type testParam struct {
+		description string
+		x, y        uint
+		rowOffset   uint
+		colOffset   uint
+		ex, ey      uint
+	}
+	tests := []testParam{
+		{"first character", 2, 0, 0, 0, 0, 0},
+		{"on the gutter", 0, 1, 0, 0, 0, 1},
+		{"within a row", 4, 1, 0, 0, 2, 1},
+		{"beyond the end of a row", 20, 0, 0, 0, 5, 0},
+		{"beyond the end of the file", 3, 4, 0, 0, 1, 2},
+		{"below the editor rows", 3, 10, 0, 0, 1, 2},
+		{"scrolled down", 3, 0, 1, 0, 1, 1},
+		{"scrolled right", 3, 0, 0, 2, 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			e := testEditor("hello", "world!", "x")
+			e.wRows, e.wCols, e.lineNumbers = 5, 20, true
+			e.rowOffset, e.colOffset = tt.rowOffset, tt.colOffset
+			if x, y := e.ScreenToDocument(tt.x, tt.y); x != tt.ex || y != tt.ey {
+				t.Errorf("ScreenToDocument(%d, %d) = (%d, %d), expected (%d, %d)", tt.x, tt.y, x, y, tt.ex, tt.ey)
+			}
+		})
+	}
+}
+
+func TestScroll(t *testing.T) {
+	type testParam struct {
+		description string
+		n           int
+		rowOffset   uint
+		cy          uint
+	}
+	tests := []testParam{
+		{"down moves the cursor into view", 3, 3, 3},
+		{"down past the end of the file", 20, 9, 9},
+		{"up past the start of the file", -3, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			e := testEditor("0", "1", "2", "3", "4", "5", "6", "7", "8", "9")
+			e.wRows, e.wCols = 5, 20
+			e.cy = 1
+			e.Scroll(tt.n)
+			if e.rowOffset != tt.rowOffset || e.cy != tt.cy {
+				t.Errorf("Scroll(%d) offset %d, cursor row %d, expected %d, %d", tt.n, e.rowOffset, e.cy, tt.rowOffset, tt.cy)
+			}
+		})
+	}
+}
+
+func TestHandleMouseSelection(t *testing.T) {
+	e := testEditor("hello", "world")
+	e.wRows, e.wCols = 5, 20
+
+	e.HandleMouse(MouseEvent{Button: MOUSE_LEFT, X: 1, Y: 0})
+	if _, _, _, _, ok := e.Selection(); ok || e.cx != 1 || e.cy != 0 {
+		t.Fatalf("click: cursor (%d, %d), selecting %t, expected (1, 0) without a selection", e.cx, e.cy, ok)
+	}
+
+	e.HandleMouse(MouseEvent{Button: MOUSE_LEFT, X: 3, Y: 1, Drag: true})
+	e.HandleMouse(MouseEvent{Button: MOUSE_LEFT, X: 3, Y: 1, Release: true})
+	if sx, sy, ex, ey, ok := e.Selection(); !ok || sx != 1 || sy != 0 || ex != 3 || ey != 1 {
+		t.Errorf("drag: selection (%d, %d) to (%d, %d), %t, expected (1, 0) to (3, 1)", sx, sy, ex, ey, ok)
+	}
+
+	// Moving without the button held doesn't select.
+	e.HandleMouse(MouseEvent{Button: MOUSE_LEFT, X: 0, Y: 0, Drag: true})
+	if e.cx != 3 || e.cy != 1 {
+		t.Errorf("drag after release moved the cursor to (%d, %d)", e.cx, e.cy)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Escape sequences enabling and disabling bracketed paste. When enabled, the terminal wraps pasted text in
+// PASTE_START and PASTE_END (ESC [ 200 ~ and ESC [ 201 ~).
+const (
+	BRACKETED_PASTE_ON  = "\x1b[?2004h"
+	BRACKETED_PASTE_OFF = "\x1b[?2004l"
+	PASTE_END_SEQ       = "\x1b[201~"
+)
+
+// Keys decoded from the start and end sequences of a bracketed paste.
+const (
+	PASTE_START Cmd = 1022
+	PASTE_END   Cmd = 1023
+)
+
+// Number of consecutive read timeouts, while reading a paste, before the paste is assumed to be finished.
+const PASTE_TIMEOUTS = 10
+
+// ReadPaste of a bracketed paste, after PASTE_START has been read, up to PASTE_END_SEQ: from pending input already
+// read ahead, then from r. Reads of r that return nothing, including io.EOF as a raw terminal returns when idle, are
+// timeouts. Bytes read after the end of the paste are returned, to be read as input. Newlines are normalised to '\n',
+// and control characters other than newlines and tabs are removed.
+func ReadPaste(pending []byte, r io.Reader) (string, []byte) {
+	buf := append(make([]byte, 0, 4096), pending...)
+	chunk := make([]byte, 4096)
+	timeouts := 0
+	for {
+		if i := bytes.Index(buf, []byte(PASTE_END_SEQ)); i != -1 {
+			return cleanPaste(buf[:i]), append([]byte{}, buf[i+len(PASTE_END_SEQ):]...)
+		}
+		if timeouts >= PASTE_TIMEOUTS {
+			break
+		}
+		n, err := r.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if err != nil && !errors.Is(err, io.EOF) {
+			break
+		}
+		if n == 0 {
+			timeouts++
+		} else {
+			timeouts = 0
+		}
+	}
+	return cleanPaste(buf), []byte{}
+}
+
+func cleanPaste(b []byte) string {
+	text := strings.ReplaceAll(string(b), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	return strings.Map(func(r rune) rune {
+		if r < 0x80 && r != '\n' && r != '\t' && isControlChar(byte(r)) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// RunPaste reads a bracketed paste from the terminal, after any input already read ahead, and inserts it at the cursor
+// as one undoable step, replacing the selection.
+func (e *Editor) RunPaste() {
+	text, rest := ReadPaste(e.pending, os.Stdin)
+	e.pending = rest
+	e.DeleteSelection()
+	e.InsertTextWithUndo(text)
+}
+
+// InsertTextWithUndo at the cursor, as one undoable step. The cursor is moved to the end of the inserted text.
+func (e *Editor) InsertTextWithUndo(text string) {
+	if len(text) == 0 {
+		return
+	}
+	x, y := e.cx, e.cy
+	ex, ey := e.InsertText(x, y, text)
+	e.cmdHistory.AddCmd(
+		func(e *Editor) error { e.RemoveText(x, y, ex, ey); e.cx, e.cy = x, y; return nil },
+		func(e *Editor) error { e.cx, e.cy = e.InsertText(x, y, text); return nil },
+	)
+	e.cx, e.cy = ex, ey
+}
+
+// InsertText, which may span multiple rows, at position (x, y) as one buffer operation. Returns the position after
+// the inserted text.
+func (e *Editor) InsertText(x, y uint, text string) (uint, uint) {
+	row := e.GetRow(y)
+	j := row.getSrcIndex(x)
+	before, after := string(row.src[:j]), string(row.src[j:])
+
+	lines := strings.Split(text, "\n")
+	newRows := make([]Row, len(lines))
+	for i, l := range lines {
+		newRows[i] = Row{src: []byte(l)} // Tabs are kept, as copied
+	}
+	newRows[0] = Row{src: []byte(before + lines[0])}
+
+	last := &newRows[len(newRows)-1]
+	ex := last.RenderLen()
+	last.src = append(last.src, after...)
+
+	e.rows = append(e.rows[:y], append(newRows, e.rows[y+1:]...)...)
+	e.Edited(y)
+	return ex, y + uint(len(lines)) - 1
+}
+
+// RemoveText between positions (sx, sy) and (ex, ey) as one buffer operation, joining the remainder of the rows.
+func (e *Editor) RemoveText(sx, sy, ex, ey uint) {
+	first, last := e.GetRow(sy), e.GetRow(ey)
+	joined := Row{src: append(append([]byte{}, first.src[:first.getSrcIndex(sx)]...), last.src[last.getSrcIndex(ex):]...)}
+
+	e.rows = append(e.rows[:sy], append([]Row{joined}, e.rows[ey+1:]...)...)
+	e.Edited(sy)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPaste(t *testing.T) {
+	type testParam struct {
+		description  string
+		input        string
+		expected     string
+		expectedRest string
+	}
+	tests := []testParam{
+		{"single line", "hello\x1b[201~", "hello", ""},
+		{"newlines normalised", "a\r\nb\rc\nd\x1b[201~", "a\nb\nc\nd", ""},
+		{"control characters removed", "a\x1b[Ab\x07\tc\x1b[201~", "a[Ab\tc", ""},
+		{"input after paste", "a\x1b[201~x\x1b[A", "a", "x\x1b[A"},
+		{"unterminated", "abc", "abc", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			text, rest := ReadPaste(nil, strings.NewReader(tt.input))
+			if text != tt.expected || string(rest) != tt.expectedRest {
+				t.Errorf("ReadPaste(%q) = %q, %q. Expected %q, %q", tt.input, text, rest, tt.expected, tt.expectedRest)
+			}
+		})
+	}
+}
+
+// terminalReader returns each of its reads in turn, as a raw terminal does, with io.EOF for reads that time out.
+type terminalReader struct {
+	reads []string
+}
+
+func (r *terminalReader) Read(p []byte) (int, error) {
+	if len(r.reads) == 0 {
+		return 0, io.EOF
+	}
+	read := r.reads[0]
+	r.reads = r.reads[1:]
+	if len(read) == 0 {
+		return 0, io.EOF
+	}
+	return copy(p, read), nil
+}
+
+func TestReadPasteTimeouts(t *testing.T) {
+	type testParam struct {
+		description  string
+		pending      string
+		reads        []string
+		expected     string
+		expectedRest string
+	}
+	tests := []testParam{
+		{"pause", "", []string{"abc", "", "def\x1b[201~x"}, "abcdef", "x"},
+		{"pending then terminal", "ab", []string{"", "c\x1b[201~"}, "abc", ""},
+		{"pending only", "a\x1b[201~x", []string{"never read"}, "a", "x"},
+		{"abandoned", "", append([]string{"abc"}, make([]string, PASTE_TIMEOUTS)...), "abc", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			r := &terminalReader{reads: append([]string{}, tt.reads...)}
+			text, rest := ReadPaste([]byte(tt.pending), r)
+			if text != tt.expected || string(rest) != tt.expectedRest {
+				t.Errorf("ReadPaste() = %q, %q. Expected %q, %q", text, rest, tt.expected, tt.expectedRest)
+			}
+		})
+	}
+}
+
+func TestReadPasteLarge(t *testing.T) {
+	line := strings.Repeat("x", 99) + "\n"
+	text, _ := ReadPaste(nil, strings.NewReader(strings.Repeat(line, 1000)+PASTE_END_SEQ))
+	if text != strings.Repeat(line, 1000) {
+		t.Errorf("Expected large paste to be read completely, read %d bytes", len(text))
+	}
+}
+
+func TestInsertText(t *testing.T) {
+	type testParam struct {
+		description string
+		rows        []string
+		x, y        uint
+		text        string
+		expected    []string
+		expectedX   uint
+		expectedY   uint
+	}
+	tests := []testParam{
+		{"within row", []string{"hello"}, 2, 0, "XY", []string{"heXYllo"}, 4, 0},
+		{"multiple rows", []string{"ab", "cd"}, 1, 0, "1\n2\n3", []string{"a1", "2", "3b", "cd"}, 1, 2},
+		{"trailing newline", []string{"ab"}, 2, 0, "x\n", []string{"abx", ""}, 0, 1},
+		{"last row", []string{"ab", "cd"}, 0, 1, "x\ny", []string{"ab", "x", "ycd"}, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			e := testEditor(tt.rows...)
+			x, y := e.InsertText(tt.x, tt.y, tt.text)
+			if rows := renderRows(e.rows); !reflect.DeepEqual(rows, tt.expected) {
+				t.Errorf("InsertText() rows = %q, expected %q", rows, tt.expected)
+			}
+			if x != tt.expectedX || y != tt.expectedY {
+				t.Errorf("InsertText() = (%d, %d), expected (%d, %d)", x, y, tt.expectedX, tt.expectedY)
+			}
+
+			e.RemoveText(tt.x, tt.y, x, y)
+			if rows := renderRows(e.rows); !reflect.DeepEqual(rows, tt.rows) {
+				t.Errorf("RemoveText() rows = %q, expected original %q", rows, tt.rows)
+			}
+		})
+	}
+}
+
+func TestInsertTextWithUndo(t *testing.T) {
+	e := testEditor("func f() {", "}")
+	e.cx, e.cy = 10, 0
+	e.InsertTextWithUndo("\n\tx := 1\n\ty := 2")
+
+	expected := []string{"func f() {", "    x := 1", "    y := 2", "}"}
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows after paste = %q, expected %q", rows, expected)
+	}
+	if e.cx != 10 || e.cy != 2 || e.cmdHistory.Depth() != 1 {
+		t.Errorf("Expected cursor at end of paste and one undo step, got (%d, %d) and %d", e.cx, e.cy, e.cmdHistory.Depth())
+	}
+
+	e.cmdHistory.Undo(e)
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"func f() {", "}"}) {
+		t.Errorf("Rows after undo = %q", rows)
+	}
+	if e.cx != 10 || e.cy != 0 {
+		t.Errorf("Expected cursor at start of paste after undo, got (%d, %d)", e.cx, e.cy)
+	}
+}
+
+func TestEditAfterPastedTab(t *testing.T) {
+	move := func(c Cmd) func(e *Editor) { return func(e *Editor) { e.HandleMoveCursor(c) } }
+	key := func(b byte) func(e *Editor) {
+		return func(e *Editor) { e.charHistory, e.pending = *NewbyteRing(1), []byte{b}; e.KeyPress() }
+	}
+
+	type testParam struct {
+		description string
+		edits       []func(e *Editor)
+		expected    []string
+		expectedX   uint
+	}
+	tests := []testParam{
+		{"enter", []func(e *Editor){key(ENTER)}, []string{"\tx", ""}, 0},
+		{"enter after tab", []func(e *Editor){move(LEFT), key(ENTER)}, []string{"\t", "x"}, 0},
+		{"left over tab", []func(e *Editor){move(LEFT), move(LEFT)}, []string{"\tx"}, 0},
+		{"right over tab", []func(e *Editor){move(HOME_KEY), move(RIGHT)}, []string{"\tx"}, 4},
+		{"backspace tab", []func(e *Editor){move(LEFT), key(BACKSPACE)}, []string{"x"}, 0},
+		{"type after tab", []func(e *Editor){move(LEFT), key('y')}, []string{"\tyx"}, 5},
+		{"next word", []func(e *Editor){move(HOME_KEY), move(CTRL_RIGHT)}, []string{"\tx"}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			e := testEditor("")
+			e.InsertTextWithUndo("\tx")
+			if string(e.rows[0].src) != "\tx" || e.cx != 5 {
+				t.Fatalf("Expected the tab to be kept and the cursor after x, got %q at %d", e.rows[0].src, e.cx)
+			}
+			for _, edit := range tt.edits {
+				edit(e)
+			}
+
+			rows := make([]string, len(e.rows))
+			for i, r := range e.rows {
+				rows[i] = string(r.src)
+			}
+			if !reflect.DeepEqual(rows, tt.expected) || e.cx != tt.expectedX {
+				t.Errorf("Rows = %q, cursor at %d. Expected %q, %d", rows, e.cx, tt.expected, tt.expectedX)
+			}
+		})
+	}
+}
+
+func TestRunPastePending(t *testing.T) {
+	e := testEditor("")
+	e.pending = []byte("a\nb" + PASTE_END_SEQ + "x")
+	e.RunPaste()
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"a", "b"}) || string(e.pending) != "x" {
+		t.Errorf("RunPaste() rows %q, pending %q. Expected the paste from pending input, and x left to read", rows, e.pending)
+	}
+}
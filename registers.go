@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Number of deleted texts kept in the kill ring.
+const KILL_RING_SIZE = 10
+
+// Registers of copied text, other than the clipboard: named registers 'a' to 'z', and a kill ring of text deleted
+// by cut and delete-row.
+type Registers struct {
+	named map[byte]string
+	kills []string // Most recent first
+}
+
+// isRegisterName of a named register.
+func isRegisterName(b byte) bool {
+	return b >= 'a' && b <= 'z'
+}
+
+// Get the text of named register r.
+func (rs *Registers) Get(r byte) (string, bool) {
+	text, exists := rs.named[r]
+	return text, exists
+}
+
+// Set named register r to text.
+func (rs *Registers) Set(r byte, text string) {
+	if rs.named == nil {
+		rs.named = make(map[byte]string)
+	}
+	rs.named[r] = text
+}
+
+// Kill pushes text onto the kill ring, dropping the oldest entry if full.
+func (rs *Registers) Kill(text string) {
+	rs.kills = append([]string{text}, rs.kills...)
+	if len(rs.kills) > KILL_RING_SIZE {
+		rs.kills = rs.kills[:KILL_RING_SIZE]
+	}
+}
+
+// Kills in the kill ring, most recent first.
+func (rs *Registers) Kills() []string {
+	return rs.kills
+}
+
+// yankState of the last paste, for YankPop to replace.
+type yankState struct {
+	x, y  uint   // Cursor after the paste
+	depth uint   // Of cmdHistory after the paste
+	text  string // Pasted
+	i     int    // Index of text in the kill ring, -1 if not from it
+}
+
+// store copied text in the selected named register, else the clipboard. Killed text is also pushed onto the kill
+// ring. The register is deselected.
+func (e *Editor) store(text string, kill bool) {
+	if kill {
+		e.registers.Kill(text)
+	}
+	if e.register != 0 {
+		e.registers.Set(e.register, text)
+		e.register = 0
+		return
+	}
+	e.SetClipboard(text)
+}
+
+// load text to paste from the selected named register, else the clipboard. The register is deselected.
+func (e *Editor) load() string {
+	if e.register != 0 {
+		text, _ := e.registers.Get(e.register)
+		e.register = 0
+		return text
+	}
+	return e.ClipboardText()
+}
+
+// pasteText at the cursor as one undoable step, replacing the selection, so that YankPop can replace it.
+func (e *Editor) pasteText(text string, i int) {
+	e.yank = nil
+	if len(text) == 0 {
+		return
+	}
+	e.DeleteSelection()
+	e.InsertTextWithUndo(text)
+	e.yank = &yankState{x: e.cx, y: e.cy, depth: e.cmdHistory.Depth(), text: text, i: i}
+}
+
+// YankPop replaces the text just pasted with the next older entry of the kill ring.
+func (e *Editor) YankPop() {
+	y := e.yank
+	if y == nil || y.x != e.cx || y.y != e.cy || y.depth != e.cmdHistory.Depth() {
+		e.SetStatusMessage("Yank-pop only follows a paste")
+		return
+	}
+	kills := e.registers.Kills()
+	if len(kills) == 0 {
+		e.SetStatusMessage("Kill ring is empty")
+		return
+	}
+
+	i := (y.i + 1) % len(kills)
+	if kills[i] == y.text && len(kills) > 1 {
+		i = (i + 1) % len(kills) // Skip the entry that's already pasted
+	}
+	e.cmdHistory.Undo(e)
+	e.pasteText(kills[i], i)
+	e.SetStatusMessage("Kill %d of %d", i+1, len(kills))
+}
+
+// KillRow deletes the current row as one undoable step, storing it (with its newline) as a cut.
+func (e *Editor) KillRow() {
+	y, src, only := e.cy, append([]byte{}, e.GetCurrentRow().src...), e.GetDocumentRows() == 1
+	e.store(string(src)+"\n", true)
+	e.RemoveCurrentRow()
+	e.cmdHistory.AddCmd(
+		func(e *Editor) error {
+			if only {
+				e.rows = e.rows[:0]
+			}
+			e.rows = append(e.rows[:y], append([]Row{{src: append([]byte{}, src...)}}, e.rows[y:]...)...)
+			e.cx, e.cy = 0, y
+			e.Edited(y)
+			return nil
+		},
+		func(e *Editor) error { e.cx, e.cy = 0, y; e.RemoveCurrentRow(); return nil },
+	)
+}
+
+// RunSelectRegister prompts for the named register ('a' to 'z') to use for the next copy, cut or paste.
+func (e *Editor) RunSelectRegister() {
+	e.MoveCursorToStatusBar()
+	e.ClearLine()
+	fmt.Print("REGISTER (a-z): ")
+
+	b := e.ReadCharBlock()
+	if !isRegisterName(b) {
+		e.register = 0
+		e.SetStatusMessage("No register selected")
+		return
+	}
+	e.register = b
+	e.SetStatusMessage("Register %q selected for the next copy, cut or paste", b)
+}
+
+// RunRegisters lists the clipboard, named registers and kill ring. The selected entry is pasted at the cursor.
+func (e *Editor) RunRegisters() {
+	items, texts, kill := make([]string, 0), make([]string, 0), make([]int, 0)
+	add := func(name, text string, i int) {
+		items = append(items, fmt.Sprintf("%-10s %s", name, strings.ReplaceAll(text, "\n", "\\n")))
+		texts, kill = append(texts, text), append(kill, i)
+	}
+
+	add("clipboard", e.paste, -1)
+	for r := byte('a'); r <= 'z'; r++ {
+		if text, exists := e.registers.Get(r); exists {
+			add(fmt.Sprintf("\"%c", r), text, -1)
+		}
+	}
+	for i, text := range e.registers.Kills() {
+		add(fmt.Sprintf("kill %d", i+1), text, i)
+	}
+
+	i, ok := e.RunList("REGISTERS (ENTER to paste)", items)
+	if ok {
+		e.pasteText(texts[i], kill[i])
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestKillRing(t *testing.T) {
+	var rs Registers
+	for i := 0; i < KILL_RING_SIZE+2; i++ {
+		rs.Kill(fmt.Sprint(i))
+	}
+	kills := rs.Kills()
+	if len(kills) != KILL_RING_SIZE || kills[0] != fmt.Sprint(KILL_RING_SIZE+1) || kills[KILL_RING_SIZE-1] != "2" {
+		t.Errorf("Kills() = %q, expected the %d most recent, newest first", kills, KILL_RING_SIZE)
+	}
+}
+
+func TestNamedRegisters(t *testing.T) {
+	e := testEditor("one two")
+	e.register = 'a'
+	e.ax, e.cx, e.selecting = 0, 3, true
+	e.Copy()
+	if text, _ := e.registers.Get('a'); text != "one" || e.paste != "" || e.register != 0 {
+		t.Errorf("Copy() to register a = %q, clipboard %q, register %q", text, e.paste, e.register)
+	}
+
+	e.ax, e.cx, e.selecting = 3, 7, true
+	e.Copy()
+	if e.paste != " two" {
+		t.Errorf("Copy() without a register = %q, expected the clipboard", e.paste)
+	}
+
+	e.cx, e.register = 7, 'a'
+	e.Paste()
+	e.Paste()
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"one twoone two"}) {
+		t.Errorf("Rows after pasting register a then the clipboard = %q", rows)
+	}
+}
+
+func TestYankPop(t *testing.T) {
+	e := testEditor("first", "second", "third", "")
+	e.KillRow()
+	e.KillRow()
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"third", ""}) {
+		t.Fatalf("Rows after killing two rows = %q", rows)
+	}
+	if kills := e.registers.Kills(); !reflect.DeepEqual(kills, []string{"second\n", "first\n"}) {
+		t.Fatalf("Kills() = %q", kills)
+	}
+
+	e.cx, e.cy = 0, 1
+	e.Paste()
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"third", "second", ""}) {
+		t.Errorf("Rows after paste = %q", rows)
+	}
+
+	e.YankPop()
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"third", "first", ""}) {
+		t.Errorf("Rows after yank-pop = %q", rows)
+	}
+	e.YankPop()
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"third", "second", ""}) {
+		t.Errorf("Rows after yank-pop wraps around = %q", rows)
+	}
+
+	e.HandleMoveCursor(UP)
+	e.YankPop()
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"third", "second", ""}) || len(e.statusMsg) == 0 {
+		t.Errorf("Expected yank-pop after moving the cursor to do nothing, got %q", rows)
+	}
+}
+
+func TestCutPushesKillRing(t *testing.T) {
+	e := testEditor("hello world")
+	e.ax, e.cx, e.selecting = 0, 6, true
+	e.Cut()
+	if kills := e.registers.Kills(); !reflect.DeepEqual(kills, []string{"hello "}) || e.paste != "hello " {
+		t.Errorf("Kills() = %q, clipboard %q after cut", kills, e.paste)
+	}
+}
+
+func TestKillRow(t *testing.T) {
+	type testParam struct {
+		description string
+		rows        []string
+		y           uint
+		expected    []string
+		expectedY   uint
+	}
+	tests := []testParam{
+		{"only row", []string{"only"}, 0, []string{""}, 0},
+		{"last row", []string{"first", "last"}, 1, []string{"first"}, 0},
+		{"middle row", []string{"a", "b", "c"}, 1, []string{"a", "c"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			e := testEditor(tt.rows...)
+			e.cy = tt.y
+			e.KillRow()
+			if rows := renderRows(e.rows); !reflect.DeepEqual(rows, tt.expected) || e.cy != tt.expectedY {
+				t.Errorf("Rows after KillRow() = %q, cursor on %d. Expected %q, %d", rows, e.cy, tt.expected, tt.expectedY)
+			}
+			if kills := e.registers.Kills(); !reflect.DeepEqual(kills, []string{tt.rows[tt.y] + "\n"}) {
+				t.Errorf("Kills() = %q", kills)
+			}
+
+			e.cmdHistory.Undo(e)
+			if rows := renderRows(e.rows); !reflect.DeepEqual(rows, tt.rows) || e.cy != tt.y {
+				t.Errorf("Rows after undo = %q, cursor on %d. Expected %q, %d", rows, e.cy, tt.rows, tt.y)
+			}
+		})
+	}
+}
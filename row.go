@@ -4,20 +4,23 @@ import (
 	"strings"
 )
 
+// Number of columns a tab is rendered as.
+const TAB_WIDTH = 4
+
 type Row struct {
 	src []byte
 }
 
+// ConstructRow of a line of text. Tabs are kept, and rendered as TAB_WIDTH spaces.
 func ConstructRow(s string) Row {
-	return Row{
-		src: []byte(strings.ReplaceAll(s, "\t", "    ")),
-	}
+	return Row{src: []byte(s)}
 }
 
 // SplitAt a given rendered index into a row. Creates two new rows, original unchanged.
-func (r Row) SplitAt(i uint) (*Row, *Row) {
+func (r Row) SplitAt(renderI uint) (*Row, *Row) {
+	i := r.getSrcIndex(renderI)
 	a := Row{src: make([]byte, i)}
-	b := Row{src: make([]byte, uint(len(r.src))-i)}
+	b := Row{src: make([]byte, len(r.src)-i)}
 	copy(a.src, r.src[:i])
 	copy(b.src, r.src[i:])
 
@@ -25,7 +28,7 @@ func (r Row) SplitAt(i uint) (*Row, *Row) {
 }
 
 func (r Row) Export() []byte {
-	return append(r.src)
+	return append([]byte{}, r.src...)
 }
 
 // RenderWithin from constraints of, starting from offset index, and being no larger than max.
@@ -44,7 +47,7 @@ func (r Row) RenderWithin(offset, max uint) string {
 
 func (r Row) Render() string {
 	srcStr := string(r.src)
-	return strings.ReplaceAll(srcStr, "\t", "    ")
+	return strings.ReplaceAll(srcStr, "\t", strings.Repeat(" ", TAB_WIDTH))
 }
 
 func (r *Row) getSrcIndex(renderI uint) int {
@@ -53,11 +56,7 @@ func (r *Row) getSrcIndex(renderI uint) int {
 		if j >= renderI {
 			return i
 		}
-		if r.src[i] == '\t' {
-			j += 4
-		} else {
-			j++
-		}
+		j += renderWidth(r.src[i])
 	}
 
 	// Accomodate adding to end of line.
@@ -115,20 +114,18 @@ func (r Row) GetNextWordFrom(renderI uint, nextWordRight bool) uint {
 		return 0
 	}
 
-	// TODO: [BUG] Indexes src index not render index. Therefore we need to address possibility
-	//   r.src[i] == '/t' and thus would shift by more than one.
 	j := r.getSrcIndex(renderI)
 	if nextWordRight {
 		for i := j + 1; i < len(r.src); i++ {
 			if r.src[i] == ' ' {
-				return uint(i)
+				return r.getRenderIndex(i)
 			}
 		}
-		return uint(len(r.src) - 1)
+		return r.getRenderIndex(len(r.src) - 1)
 	} else {
 		for i := j - 1; i >= 0; i-- {
 			if r.src[i] == ' ' {
-				return uint(i)
+				return r.getRenderIndex(i)
 			}
 		}
 		return 0
@@ -153,3 +150,33 @@ func (r *Row) RenderIndexOf(s string, from int) int {
 func (r *Row) Append(ro *Row) {
 	r.src = append(r.src, ro.src...)
 }
+
+// getRenderIndex of src index srcI, which is moved to the end of the row if beyond it.
+func (r *Row) getRenderIndex(srcI int) uint {
+	j := uint(0)
+	for i := 0; i < len(r.src) && i < srcI; i++ {
+		j += renderWidth(r.src[i])
+	}
+	return j
+}
+
+// snapRenderIndex to the start of the character rendered at renderI, or the end of the row if beyond it, so that it
+// isn't within a tab.
+func (r *Row) snapRenderIndex(renderI uint) uint {
+	j := uint(0)
+	for _, b := range r.src {
+		if j+renderWidth(b) > renderI {
+			return j
+		}
+		j += renderWidth(b)
+	}
+	return j
+}
+
+// renderWidth of a src byte, in columns.
+func renderWidth(b byte) uint {
+	if b == '\t' {
+		return TAB_WIDTH
+	}
+	return 1
+}
@@ -1,5 +1,10 @@
 package main
 
+import "context"
+
+// MAX_SEARCH_RESULTS bounds the results produced by a single search, so huge buffers can't produce unbounded work.
+const MAX_SEARCH_RESULTS = 10000
+
 type SearchResult struct {
 	rowRef *Row
 	startI uint
@@ -7,22 +12,40 @@ type SearchResult struct {
 }
 
 // SearchRows concurrently searches for a given query string in a slice of Rows.
-// It returns a channel of search results.
-func SearchRows(rows []Row, q string) <-chan SearchResult {
+// It returns a channel of search results, closed once the search completes, MAX_SEARCH_RESULTS are found or ctx is
+// cancelled. Callers that stop reading before the channel is closed must cancel ctx to release the search goroutine.
+func SearchRows(ctx context.Context, rows []Row, q string) <-chan SearchResult {
 	results := make(chan SearchResult)
+	if len(q) == 0 {
+		close(results)
+		return results
+	}
 
 	go func() {
 		defer close(results)
 
+		n := 0
 		for i := range rows {
+			if ctx.Err() != nil {
+				return
+			}
 			y := rows[i].RenderIndexOf(q, 0)
 
 			// Handle multiple search terms in one Row.
 			for y != -1 {
-				results <- SearchResult{
+				select {
+				case results <- SearchResult{
 					rowRef: &rows[i],
 					startI: uint(y),
 					rowI:   uint(i),
+				}:
+				case <-ctx.Done():
+					return
+				}
+
+				n++
+				if n >= MAX_SEARCH_RESULTS {
+					return
 				}
 				y = rows[i].RenderIndexOf(q, y+1)
 			}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func TestSearchRows(t *testing.T) {
@@ -13,7 +16,7 @@ func TestSearchRows(t *testing.T) {
 	}
 	q := "Go"
 
-	results := SearchRows(rows, q)
+	results := SearchRows(context.Background(), rows, q)
 
 	// Collect all results.
 	var res []SearchResult
@@ -37,3 +40,54 @@ func TestSearchRows(t *testing.T) {
 		t.Errorf("Unexpected result: %+v", res[1])
 	}
 }
+
+// waitForGoroutines to return to at most n, failing the test if they don't within a second.
+func waitForGoroutines(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Leaked goroutines: %d running, expected at most %d", runtime.NumGoroutine(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSearchRowsCancelDoesNotLeak(t *testing.T) {
+	rows := make([]Row, 1000)
+	for i := range rows {
+		rows[i] = ConstructRow("Go Go Go")
+	}
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		for range SearchRows(ctx, rows, "Go") {
+			break // As RunSearch does when the user leaves search.
+		}
+		cancel()
+	}
+	waitForGoroutines(t, before)
+}
+
+func TestSearchRowsBounded(t *testing.T) {
+	rows := make([]Row, MAX_SEARCH_RESULTS)
+	for i := range rows {
+		rows[i] = ConstructRow("aa")
+	}
+
+	n := 0
+	for range SearchRows(context.Background(), rows, "a") {
+		n++
+	}
+	if n != MAX_SEARCH_RESULTS {
+		t.Errorf("Expected %d results, got %d", MAX_SEARCH_RESULTS, n)
+	}
+}
+
+func TestSearchRowsEmptyQuery(t *testing.T) {
+	rows := []Row{ConstructRow("Hello")}
+	for r := range SearchRows(context.Background(), rows, "") {
+		t.Errorf("Unexpected result for empty query: %+v", r)
+	}
+}
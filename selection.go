@@ -0,0 +1,172 @@
+package main
+
+import (
+	"strings"
+)
+
+// Number of spaces a row is indented by.
+const INDENT_WIDTH = 4
+
+// selectionMoves are the cursor moves that, with Shift, extend the selection.
+var selectionMoves = []Cmd{UP, DOWN, LEFT, RIGHT, HOME_KEY, END_KEY, PAGE_UP, PAGE_DOWN, CTRL_LEFT, CTRL_RIGHT}
+
+// selectionMove of key c, without its Shift modifier, if c is one of selectionMoves.
+func selectionMove(c Cmd) (Cmd, bool) {
+	move := c &^ MOD_SHIFT
+	for _, m := range selectionMoves {
+		if m == move {
+			return move, true
+		}
+	}
+	return 0, false
+}
+
+// HandleSelection of key c, if c extends the selection, or acts on selected text. Returns true if c was handled.
+func (e *Editor) HandleSelection(c Cmd) bool {
+	// While marking, cursor moves without Shift also extend the selection.
+	if move, ok := selectionMove(c); ok && (c&MOD_SHIFT != 0 || e.marking) {
+		e.ExtendSelection(move)
+		return true
+	}
+	if c == ESCAPE && e.selecting {
+		e.ClearSelection()
+		return true
+	}
+	if _, _, _, _, selected := e.Selection(); !selected {
+		return false
+	}
+
+	switch c {
+	case BACKSPACE, DELETE:
+		e.DeleteSelection()
+	case TAB:
+		e.IndentSelection(true)
+	case SHIFT_TAB:
+		e.IndentSelection(false)
+	default:
+		return false
+	}
+	return true
+}
+
+// ExtendSelection by moving the cursor. Starts a selection at the cursor if there isn't one.
+func (e *Editor) ExtendSelection(move Cmd) {
+	if !e.selecting {
+		e.ax, e.ay, e.selecting = e.cx, e.cy, true
+	}
+	e.HandleMoveCursor(move)
+}
+
+// ClearSelection, leaving the cursor where it is.
+func (e *Editor) ClearSelection() {
+	e.selecting, e.marking = false, false
+}
+
+// Copy the selection. Without a selection, marks the cursor as the start of one, so that moving the cursor selects
+// text until Copy is run again.
+func (e *Editor) Copy() {
+	if sx, sy, ex, ey, selected := e.Selection(); selected {
+		e.store(e.GetStringBetween(sx, sy, ex, ey), false)
+		e.ClearSelection()
+		return
+	}
+	e.ax, e.ay, e.selecting, e.marking = e.cx, e.cy, true, true
+	e.SetStatusMessage("Move the cursor to select, then copy again. ESC to cancel")
+}
+
+// Cut the selection, copying it (and pushing it onto the kill ring) then deleting it.
+func (e *Editor) Cut() {
+	sx, sy, ex, ey, selected := e.Selection()
+	if !selected {
+		e.SetStatusMessage("Nothing selected")
+		return
+	}
+	e.store(e.GetStringBetween(sx, sy, ex, ey), true)
+	e.DeleteSelection()
+}
+
+// DeleteSelection as one undoable step, moving the cursor to where the selection started. The selection is cleared.
+// Returns false if no text is selected.
+func (e *Editor) DeleteSelection() bool {
+	sx, sy, ex, ey, selected := e.Selection()
+	e.ClearSelection()
+	if !selected {
+		return false
+	}
+
+	text := e.GetStringBetween(sx, sy, ex, ey)
+	e.RemoveText(sx, sy, ex, ey)
+	e.cmdHistory.AddCmd(
+		func(e *Editor) error { e.cx, e.cy = e.InsertText(sx, sy, text); return nil },
+		func(e *Editor) error { e.RemoveText(sx, sy, ex, ey); e.cx, e.cy = sx, sy; return nil },
+	)
+	e.cx, e.cy = sx, sy
+	return true
+}
+
+// IndentSelection of every row with selected text, by INDENT_WIDTH spaces, or outdent them by a leading tab or up to
+// INDENT_WIDTH leading spaces, as one undoable step. The selection is kept, moving with the text.
+func (e *Editor) IndentSelection(indent bool) {
+	_, sy, ex, ey, selected := e.Selection()
+	if !selected {
+		return
+	}
+	if ex == 0 && ey > sy {
+		ey-- // Nothing is selected on the last row
+	}
+
+	before := e.copyRows(sy, ey)
+	for y := sy; y <= ey; y++ {
+		row := e.GetRow(y)
+		n := INDENT_WIDTH
+		if indent {
+			row.src = append([]byte(strings.Repeat(" ", n)), row.src...)
+		} else if len(row.src) > 0 && row.src[0] == '\t' {
+			row.src = row.src[1:]
+			n = -TAB_WIDTH
+		} else {
+			n = len(row.src) - len(strings.TrimLeft(string(row.src), " "))
+			if n > INDENT_WIDTH {
+				n = INDENT_WIDTH
+			}
+			row.src = row.src[n:]
+			n = -n
+		}
+		e.ax, e.cx = shiftColumn(e.ax, e.ay == y, n), shiftColumn(e.cx, e.cy == y, n)
+	}
+	e.Edited(sy)
+
+	after := e.copyRows(sy, ey)
+	e.cmdHistory.AddCmd(
+		func(e *Editor) error { e.setRows(sy, before); return nil },
+		func(e *Editor) error { e.setRows(sy, after); return nil },
+	)
+}
+
+// shiftColumn x by n, if on a row that was shifted, without moving it before the start of the row.
+func shiftColumn(x uint, shifted bool, n int) uint {
+	if !shifted {
+		return x
+	}
+	if n < 0 && uint(-n) > x {
+		return 0
+	}
+	return uint(int(x) + n)
+}
+
+// copyRows sy to ey, inclusive.
+func (e *Editor) copyRows(sy, ey uint) []Row {
+	rows := make([]Row, 0, ey-sy+1)
+	for y := sy; y <= ey; y++ {
+		rows = append(rows, Row{src: append([]byte{}, e.GetRow(y).src...)})
+	}
+	return rows
+}
+
+// setRows from y to copies of rows.
+func (e *Editor) setRows(y uint, rows []Row) {
+	for i, r := range rows {
+		e.rows[y+uint(i)] = Row{src: append([]byte{}, r.src...)}
+	}
+	e.Edited(y)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHandleSelectionMoves(t *testing.T) {
+	type testParam struct {
+		description string
+		keys        []Cmd
+		marking     bool
+		expected    [4]uint // sx, sy, ex, ey
+		selected    bool
+	}
+	tests := []testParam{
+		{"shift right", []Cmd{SHIFT_RIGHT, SHIFT_RIGHT}, false, [4]uint{2, 0, 4, 0}, true},
+		{"shift left", []Cmd{SHIFT_LEFT}, false, [4]uint{1, 0, 2, 0}, true},
+		{"shift down", []Cmd{SHIFT_DOWN}, false, [4]uint{2, 0, 2, 1}, true},
+		{"shift end", []Cmd{SHIFT_END}, false, [4]uint{2, 0, 11, 0}, true},
+		{"shift home", []Cmd{SHIFT_HOME}, false, [4]uint{0, 0, 2, 0}, true},
+		{"ctrl shift right", []Cmd{CTRL_RIGHT | MOD_SHIFT}, false, [4]uint{2, 0, 5, 0}, true},
+		{"back to the anchor", []Cmd{SHIFT_RIGHT, SHIFT_LEFT}, false, [4]uint{}, false},
+		{"move without shift", []Cmd{RIGHT}, false, [4]uint{}, false},
+		{"move while marking", []Cmd{RIGHT, DOWN}, true, [4]uint{2, 0, 3, 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			e := testEditor("hello world", "second row")
+			e.cx = 2
+			if tt.marking {
+				e.Copy()
+			}
+			for _, k := range tt.keys {
+				e.HandleSelection(k)
+			}
+			sx, sy, ex, ey, selected := e.Selection()
+			if selected != tt.selected || (selected && [4]uint{sx, sy, ex, ey} != tt.expected) {
+				t.Errorf("Selection() = (%d, %d, %d, %d, %t), expected %v, %t", sx, sy, ex, ey, selected, tt.expected, tt.selected)
+			}
+		})
+	}
+}
+
+func TestDeleteSelection(t *testing.T) {
+	e := testEditor("hello world")
+	e.ax, e.cx, e.selecting = 8, 2, true
+	if !e.HandleSelection(BACKSPACE) {
+		t.Fatalf("Expected BACKSPACE to delete the selection")
+	}
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"herld"}) || e.cx != 2 || e.selecting {
+		t.Errorf("Rows after delete = %q, cursor %d, selecting %t", rows, e.cx, e.selecting)
+	}
+
+	e.cmdHistory.Undo(e)
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"hello world"}) {
+		t.Errorf("Rows after undo = %q", rows)
+	}
+	if e.HandleSelection(BACKSPACE) {
+		t.Errorf("Expected BACKSPACE without a selection to be unhandled")
+	}
+}
+
+func TestCut(t *testing.T) {
+	e := testEditor("hello world")
+	e.ax, e.cx, e.selecting = 5, 11, true
+	e.Cut()
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"hello"}) || e.paste != " world" {
+		t.Errorf("Rows after cut = %q, copied %q", rows, e.paste)
+	}
+}
+
+func TestIndentSelection(t *testing.T) {
+	e := testEditor("a", "  b", "c")
+	e.ax, e.ay, e.cx, e.cy, e.selecting = 1, 0, 0, 2, true
+
+	e.HandleSelection(TAB)
+	expected := []string{"    a", "      b", "c"}
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows after indent = %q, expected %q", rows, expected)
+	}
+	if sx, sy, ex, ey, _ := e.Selection(); sx != 5 || sy != 0 || ex != 0 || ey != 2 {
+		t.Errorf("Selection after indent = (%d, %d, %d, %d), expected (5, 0, 0, 2)", sx, sy, ex, ey)
+	}
+
+	e.HandleSelection(SHIFT_TAB)
+	e.HandleSelection(SHIFT_TAB)
+	expected = []string{"a", "b", "c"}
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows after outdent = %q, expected %q", rows, expected)
+	}
+
+	e.cmdHistory.Undo(e)
+	expected = []string{"a", "  b", "c"}
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows after undo = %q, expected %q", rows, expected)
+	}
+}
+
+func TestOutdentTab(t *testing.T) {
+	e := testEditor("", "b")
+	e.InsertText(0, 0, "\ta")
+	e.ax, e.ay, e.cx, e.cy, e.selecting = 5, 0, 1, 1, true
+
+	e.HandleSelection(SHIFT_TAB)
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, []string{"a", "b"}) {
+		t.Errorf("Rows after outdent = %q", rows)
+	}
+	if e.ax != 1 {
+		t.Errorf("Expected the selection to move with the text, from 1, got %d", e.ax)
+	}
+}
+
+func TestIsReservedKey(t *testing.T) {
+	for _, k := range []Cmd{SHIFT_UP, SHIFT_END, CTRL_LEFT | MOD_SHIFT, SHIFT_TAB} {
+		if !isReservedKey(k) {
+			t.Errorf("Expected %s to be reserved for selection", KeyChordName(k))
+		}
+	}
+	if isReservedKey(F5 | MOD_SHIFT) {
+		t.Errorf("Expected Shift-F5 not to be reserved")
+	}
+}
+
+func TestCutPasteMultipleRows(t *testing.T) {
+	original := []string{"func f() {", "\tx := 1", "\ty := 2", "}"}
+	e := testEditor()
+	for _, r := range original {
+		e.rows = append(e.rows, Row{src: []byte(r)})
+	}
+	exported := func() []string {
+		rows := make([]string, len(e.rows))
+		for i, r := range e.rows {
+			rows[i] = string(r.Export())
+		}
+		return rows
+	}
+
+	// Select from after "{" to the end of "x := 1"
+	e.ax, e.ay, e.cx, e.cy, e.selecting = 10, 0, 10, 1, true
+	e.Cut()
+	if e.paste != "\n\tx := 1" {
+		t.Errorf("Cut() copied %q", e.paste)
+	}
+	expected := []string{"func f() {", "\ty := 2", "}"}
+	if rows := exported(); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows after cut = %q, expected %q", rows, expected)
+	}
+
+	// Paste after "y := 2"
+	e.cx, e.cy = 10, 1
+	e.Paste()
+	expected = []string{"func f() {", "\ty := 2", "\tx := 1", "}"}
+	if rows := exported(); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows after paste = %q, expected %q", rows, expected)
+	}
+	if e.cx != 10 || e.cy != 2 {
+		t.Errorf("Cursor after paste = (%d, %d), expected (10, 2)", e.cx, e.cy)
+	}
+
+	e.cmdHistory.Undo(e)
+	e.cmdHistory.Undo(e)
+	if rows := exported(); !reflect.DeepEqual(rows, original) {
+		t.Errorf("Rows after undoing paste and cut = %q, expected %q", rows, original)
+	}
+}
+
+func TestCopyPasteReplacesSelection(t *testing.T) {
+	e := testEditor("one two", "three")
+	e.ax, e.ay, e.cx, e.cy, e.selecting = 4, 0, 2, 1, true
+	e.Copy()
+	if e.paste != "two\nth" || e.selecting {
+		t.Errorf("Copy() = %q, selecting %t", e.paste, e.selecting)
+	}
+
+	e.ax, e.ay, e.cx, e.cy, e.selecting = 0, 0, 3, 0, true
+	e.Paste()
+	expected := []string{"two", "th two", "three"}
+	if rows := renderRows(e.rows); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows after paste = %q, expected %q", rows, expected)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ColourDepth is the number of colours a terminal supports.
+type ColourDepth int
+
+const (
+	COLOURS_16 ColourDepth = iota
+	COLOURS_256
+	COLOURS_TRUE
+)
+
+// NamedColours are indices into the terminal's 256 colour palette.
+var NamedColours = map[string]int{
+	"Black": 0, "DarkRed": 1, "DarkGreen": 2, "DarkYellow": 3, "DarkBlue": 4, "DarkMagenta": 5, "DarkCyan": 6,
+	"LightGray": 7, "DarkGray": 8, "Red": 9, "Green": 10, "Yellow": 11, "Blue": 12, "Magenta": 13, "Cyan": 14,
+	"White": 15, "Orange": 202,
+}
+
+// Approximate RGB values of the 16 standard terminal colours (xterm defaults).
+var basicPalette = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0}, {0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0}, {92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// Levels of each component in the 6x6x6 colour cube of the 256 colour palette.
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// DetectColourDepth of the terminal from $COLORTERM and $TERM.
+func DetectColourDepth() ColourDepth {
+	ct := os.Getenv("COLORTERM")
+	if ct == "truecolor" || ct == "24bit" {
+		return COLOURS_TRUE
+	}
+	if strings.Contains(os.Getenv("TERM"), "256color") {
+		return COLOURS_256
+	}
+	return COLOURS_16
+}
+
+// Style of text in a colour scheme: foreground and background colours, and attributes. Colours are either a name in
+// NamedColours, a 256 colour palette index or #RRGGBB. In JSON, a Style is either a colour string (the foreground), or
+// an object, i.e. {"fg": "#ff8700", "bg": "236", "bold": true}.
+type Style struct {
+	Fg        string `json:"fg"`
+	Bg        string `json:"bg"`
+	Bold      bool   `json:"bold"`
+	Italic    bool   `json:"italic"`
+	Underline bool   `json:"underline"`
+	Reverse   bool   `json:"reverse"` // Swap foreground and background, i.e. for selections
+}
+
+func (s *Style) UnmarshalJSON(b []byte) error {
+	var fg string
+	if err := json.Unmarshal(b, &fg); err == nil {
+		*s = Style{Fg: fg}
+		return nil
+	}
+
+	type style Style // Avoid recursing into UnmarshalJSON
+	var x style
+	if err := json.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	*s = Style(x)
+	return nil
+}
+
+// Escape sequence for the style, for a terminal of the given colour depth.
+func (s Style) Escape(depth ColourDepth) (Colour, error) {
+	codes := make([]string, 0)
+	if s.Bold {
+		codes = append(codes, "1")
+	}
+	if s.Italic {
+		codes = append(codes, "3")
+	}
+	if s.Underline {
+		codes = append(codes, "4")
+	}
+	if s.Reverse {
+		codes = append(codes, "7")
+	}
+	for i, c := range []string{s.Fg, s.Bg} {
+		if len(c) == 0 {
+			continue
+		}
+		code, err := colourCode(c, i == 1, depth)
+		if err != nil {
+			return "", err
+		}
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return "", nil
+	}
+	return Colour(fmt.Sprintf("\033[%sm", strings.Join(codes, ";"))), nil
+}
+
+// colourCode is the SGR parameter for colour c, as a foreground or background, degraded to the colour depth.
+func colourCode(c string, bg bool, depth ColourDepth) (string, error) {
+	base := 30
+	if bg {
+		base = 40
+	}
+
+	if c == "Default" {
+		return strconv.Itoa(base + 9), nil
+	}
+	if strings.HasPrefix(c, "#") {
+		rgb, err := parseHexColour(c)
+		if err != nil {
+			return "", err
+		}
+		switch depth {
+		case COLOURS_TRUE:
+			return fmt.Sprintf("%d;2;%d;%d;%d", base+8, rgb[0], rgb[1], rgb[2]), nil
+		case COLOURS_256:
+			return fmt.Sprintf("%d;5;%d", base+8, nearestPaletteIndex(rgb, 16, 256)), nil
+		}
+		return basicColourCode(nearestPaletteIndex(rgb, 0, 16), base), nil
+	}
+
+	i, exists := NamedColours[c]
+	if !exists {
+		n, err := strconv.Atoi(c)
+		if err != nil || n < 0 || n > 255 {
+			return "", fmt.Errorf("invalid colour %q", c)
+		}
+		i = n
+	}
+	if i < 16 {
+		return basicColourCode(i, base), nil
+	}
+	if depth == COLOURS_16 {
+		return basicColourCode(nearestPaletteIndex(paletteRGB(i), 0, 16), base), nil
+	}
+	return fmt.Sprintf("%d;5;%d", base+8, i), nil
+}
+
+// basicColourCode for one of the 16 standard colours. Bright colours (8-15) use the 90-97 (100-107) range.
+func basicColourCode(i, base int) string {
+	if i < 8 {
+		return strconv.Itoa(base + i)
+	}
+	return strconv.Itoa(base + 60 + i - 8)
+}
+
+func parseHexColour(c string) ([3]int, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(c, "#"), 16, 32)
+	if err != nil || len(c) != 7 {
+		return [3]int{}, fmt.Errorf("invalid colour %q, expected #RRGGBB", c)
+	}
+	return [3]int{int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff)}, nil
+}
+
+// paletteRGB of index i in the 256 colour palette.
+func paletteRGB(i int) [3]int {
+	switch {
+	case i < 16:
+		return basicPalette[i]
+	case i < 232:
+		i -= 16
+		return [3]int{cubeLevels[i/36], cubeLevels[i/6%6], cubeLevels[i%6]}
+	default:
+		g := 8 + (i-232)*10
+		return [3]int{g, g, g}
+	}
+}
+
+// nearestPaletteIndex to rgb within indices [from, to) of the 256 colour palette. The standard 16 colours vary between
+// terminals, so are best avoided when approximating in 256 colours.
+func nearestPaletteIndex(rgb [3]int, from, to int) int {
+	best, bestD := from, -1
+	for i := from; i < to; i++ {
+		p := paletteRGB(i)
+		d := 0
+		for j := range p {
+			d += (p[j] - rgb[j]) * (p[j] - rgb[j])
+		}
+		if bestD == -1 || d < bestD {
+			best, bestD = i, d
+		}
+	}
+	return best
+}
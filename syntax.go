@@ -9,23 +9,84 @@ import (
 	"unicode"
 )
 
+// Number of highlighted rows cached by a Syntax.
+const HIGHLIGHT_CACHE_SIZE = 1000
+
 type Syntax struct {
 	l     LanguageSyntax
-	cache LRUCache
+	cache LRUCache[highlightKey, []Colour]
 	c     ColourScheme
+	rules []compiledRule
+
+	rowStates []HlState     // Lexer state at the start of each row, valid up to the first edited row.
+	literal   *Syntax       // Highlights only strings and comments, for Literals. Created on first use.
+	edits     uint          // Number of calls to Invalidate, so that caches of the rows can tell they're stale.
+	bracket   *bracketCache // Of MatchBracket
 }
 
 type LanguageSyntax struct {
-	Exts        []string `json:"extensions"`
-	Keywords    []string `json:"Keywords"`
-	StringChars []string `json:"stringCharacters"`
-	Comment     string   `json:"commentCharacter"`
-	HlStrings   bool     `json:"highlightStrings"`
-	HlNumbers   bool     `json:"highlightNumbers"`
+	Name             string       `json:"name"`
+	Exts             []string     `json:"extensions"`
+	Filenames        []string     `json:"filenames"`    // Exact file names, i.e. Makefile
+	Interpreters     []string     `json:"interpreters"` // Shebang interpreters, i.e. python3
+	Keywords         []string     `json:"Keywords"`
+	Types            []string     `json:"types"`
+	Builtins         []string     `json:"builtins"`
+	Constants        []string     `json:"constants"`
+	Rules            []RegexRule  `json:"rules"`
+	StringChars      []string     `json:"stringCharacters"`
+	CharChars        []string     `json:"charCharacters"`
+	Comment          string       `json:"commentCharacter"`
+	BlockComments    []Delimiters `json:"blockComments"`
+	MultilineStrings []Delimiters `json:"multilineStrings"`
+	NumberFormats    []string     `json:"numberFormats"` // Any of "hex", "binary", "octal", "float". All if empty.
+	HlStrings        bool         `json:"highlightStrings"`
+	HlNumbers        bool         `json:"highlightNumbers"`
+}
+
+// RegexRule highlights text matching Pattern at the start of a word (i.e. function calls, decorators, annotations)
+// with the ColourScheme slot named Colour. If Group is non-zero, only that capture group is highlighted.
+type RegexRule struct {
+	Pattern string `json:"pattern"`
+	Colour  string `json:"colour"`
+	Group   int    `json:"group"`
+}
+
+type compiledRule struct {
+	re    *regexp.Regexp
+	c     Colour
+	group int
+}
+
+// Delimiters of a region that can span multiple rows (i.e. block comments, multi-line strings).
+type Delimiters struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Raw   bool   `json:"raw"` // Backslash doesn't escape within a raw multi-line string.
+}
+
+type HlStateKind uint8
+
+const (
+	HL_NORMAL HlStateKind = iota
+	HL_BLOCK_COMMENT
+	HL_MULTILINE_STRING
+)
+
+// HlState is the lexer state carried from the end of one row to the start of the next.
+type HlState struct {
+	Kind  HlStateKind
+	Delim int // Index of the open region's Delimiters in LanguageSyntax.BlockComments or .MultilineStrings
+}
+
+// highlightKey of a row's colours: its full contents and the lexer state at its start.
+type highlightKey struct {
+	in HlState
+	x  string
 }
 
 var defaultSyntax = LanguageSyntax{
-	Exts:        []string{""},
+	Name:        "text",
 	Keywords:    []string{},
 	Comment:     "#",
 	StringChars: []string{"\""},
@@ -33,59 +94,121 @@ var defaultSyntax = LanguageSyntax{
 	HlStrings:   false,
 }
 
-func CreateSyntax(filename string) *Syntax {
-	return &Syntax{
-		l:     GetLanguageSyntax(filename),
-		cache: *CreateCache(100),
+// CreateSyntax for a file, detecting its language from its filename and contents.
+func CreateSyntax(filename string, rows []Row) *Syntax {
+	return CreateSyntaxFor(GetLanguageSyntax(filename, rows))
+}
+
+// CreateSyntaxFor a given language syntax.
+func CreateSyntaxFor(l LanguageSyntax) *Syntax {
+	s := &Syntax{
+		l:     l,
+		cache: *CreateCache[highlightKey, []Colour](HIGHLIGHT_CACHE_SIZE),
 		c:     GetColourScheme(),
 	}
+	s.rules = s.compileRules()
+	return s
+}
+
+// Name of the language being highlighted.
+func (s *Syntax) Name() string {
+	return s.l.Name
+}
+
+// CacheStats of hits and misses of the highlighted rows cache.
+func (s *Syntax) CacheStats() (int, int) {
+	return s.cache.Stats()
+}
+
+// compileRules of the language syntax, anchored to the start of the text being matched. Rules with invalid patterns
+// or unknown colours are ignored.
+func (s *Syntax) compileRules() []compiledRule {
+	rules := make([]compiledRule, 0, len(s.l.Rules))
+	for _, r := range s.l.Rules {
+		re, err := regexp.Compile("^(?:" + r.Pattern + ")")
+		c, exists := s.c.Slot(r.Colour)
+		if err != nil || !exists || r.Group > re.NumSubexp() {
+			continue
+		}
+		rules = append(rules, compiledRule{re: re, c: c, group: r.Group})
+	}
+	return rules
+}
+
+// Highlight string according to a given highlighting syntax, starting from the lexer state in.
+func (s *Syntax) Highlight(x string, in HlState) string {
+	return ApplyColours(x, s.Colours(x, in))
 }
 
-// Highlight string according to a given highlighting syntax
-func (s *Syntax) Highlight(x string) string {
-	v, exists := s.cache.Get(x)
+// Colours of each character of the full row x, starting from the lexer state in. The returned slice is shared with
+// the cache, and must not be modified.
+func (s *Syntax) Colours(x string, in HlState) []Colour {
+	k := highlightKey{in: in, x: x}
+	hl, exists := s.cache.Get(k)
 	if exists {
-		return v
+		return hl
 	}
 
 	// Cache miss
-	v = s.ApplySyntax(x)
-	s.cache.Set(x, v)
-	return v
+	hl, _ = s.Tokenize(x, in)
+	s.cache.Set(k, hl)
+	return hl
 }
 
-func LoadSyntaxesFromFile(file string) []LanguageSyntax {
-	bytes, err := os.ReadFile(file)
-	if err != nil {
-		return []LanguageSyntax{}
+// ColoursWithin highlights the full row x, starting from the lexer state in, then returns the part of x visible from
+// offset, no wider than max, and its colours. Tokens cut by the left edge keep their colour.
+func (s *Syntax) ColoursWithin(x string, in HlState, offset, max uint) (string, []Colour) {
+	if offset >= uint(len(x)) {
+		return "", []Colour{}
+	}
+	end := uint(len(x))
+	if end > offset+max {
+		end = offset + max
 	}
+	return x[offset:end], s.Colours(x, in)[offset:end]
+}
 
-	var syntaxes []LanguageSyntax
-	err = json.Unmarshal(bytes, &syntaxes)
-	if err != nil {
-		panic(fmt.Errorf("%w", err))
+// StateAt returns the lexer state at the start of row y, computing and caching states of preceding rows as needed.
+func (s *Syntax) StateAt(rows []Row, y uint) HlState {
+	if len(s.rowStates) == 0 {
+		s.rowStates = append(s.rowStates, HlState{})
+	}
+	for uint(len(s.rowStates)) <= y && len(s.rowStates) <= len(rows) {
+		i := len(s.rowStates) - 1
+		s.rowStates = append(s.rowStates, s.EndState(rows[i].Render(), s.rowStates[i]))
+	}
+	if y >= uint(len(s.rowStates)) {
+		return HlState{}
 	}
-	return syntaxes
+	return s.rowStates[y]
 }
 
-// GetLanguageSyntax of file based on filename.
-func GetLanguageSyntax(filename string) LanguageSyntax {
-	syntaxes := append(LoadSyntaxesFromFile("syntax.json"), builtinLanguageSyntaxs...)
-	for _, syntax := range syntaxes {
-		if FileHasExtension(filename, syntax.Exts) {
-			return syntax
-		}
+// Invalidate cached lexer states after row y, as row y has been edited. The state at the start of row y is unchanged.
+func (s *Syntax) Invalidate(y uint) {
+	s.edits++
+	if uint(len(s.rowStates)) > y+1 {
+		s.rowStates = s.rowStates[:y+1]
 	}
-	return defaultSyntax
 }
 
-func FileHasExtension(filename string, exts []string) bool {
-	for _, ext := range exts {
-		if strings.HasSuffix(filename, ext) {
-			return true
-		}
+// EndState of lexer after a row x, that started with state in.
+func (s *Syntax) EndState(x string, in HlState) HlState {
+	_, out := s.Tokenize(x, in)
+	return out
+}
+
+func LoadSyntaxesFromFile(file string) ([]LanguageSyntax, error) {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return []LanguageSyntax{}, err
+	}
+
+	var syntaxes []LanguageSyntax
+	err = json.Unmarshal(bytes, &syntaxes)
+	if err != nil {
+		return []LanguageSyntax{}, fmt.Errorf("%s: %w", file, err)
 	}
-	return false
+	return syntaxes, nil
 }
 
 // AllWordIndices returns all indices of a subword within a larger string.
@@ -126,71 +249,8 @@ func isWord(s string, start, end int) bool {
 	return true
 }
 
-func (s *Syntax) ApplySyntax(x string) string {
-	hl := make([]Colour, len(x))
-
-	// Keyword highlights
-	for _, k := range s.l.Keywords {
-		for _, idx := range AllWordIndices(x, k) {
-			for i := 0; i < len(k); i++ {
-				hl[idx+i] = s.c.Keyword
-			}
-		}
-	}
-
-	// Comments
-	if len(s.l.Comment) > 0 {
-
-		cIdx := strings.Index(x, s.l.Comment)
-		if cIdx != -1 {
-			for i := cIdx; i < len(x); i++ {
-				hl[i] = s.c.Comments
-			}
-		}
-	}
-
-	// TODOs
-	toDoIdx := strings.Index(x, s.l.Comment+" TODO")
-	if toDoIdx != -1 {
-		for i := toDoIdx + len(s.l.Comment); i < len(x); i++ {
-			hl[i] = s.c.Todos
-		}
-	}
-
-	// Numbers
-	if s.l.HlNumbers {
-		HighlightRegex(x, "[-]?\\d[\\d,]*[\\.]?[\\d{2}]*", &hl, s.c.Numbers)
-	}
-
-	// Strings
-	if s.l.HlStrings {
-		for _, b := range s.l.StringChars {
-			HighlightString(x, string(b), &hl, s.c.Strings)
-		}
-	}
-
-	return ApplyColours(x, hl)
-}
-
-func HighlightString(s, char string, hl *[]Colour, c Colour) {
-	re, _ := regexp.Compile(char)
-	results := re.FindAllIndex([]byte(s), -1)
-
-	//  Every second result to avoid highlighting between strings.
-	for j := 0; j+1 < len(results); j += 2 {
-		idx := results[j]
-		idx2 := results[j+1]
-		for i := idx[0]; i <= idx2[0]; i++ {
-			(*hl)[i] = c
-		}
-	}
-}
-
-func HighlightRegex(s, regex string, hl *[]Colour, c Colour) {
-	re, _ := regexp.Compile(regex)
-	for _, idx := range re.FindAllIndex([]byte(s), -1) {
-		for i := idx[0]; i < idx[1]; i++ {
-			(*hl)[i] = c
-		}
-	}
+// ApplySyntax to a row x, starting in lexer state in. Returns the highlighted row and the lexer state at its end.
+func (s *Syntax) ApplySyntax(x string, in HlState) (string, HlState) {
+	hl, out := s.Tokenize(x, in)
+	return ApplyColours(x, hl), out
 }
@@ -2,54 +2,82 @@ package main
 
 var builtinLanguageSyntaxs = []LanguageSyntax{
 	{
-		Exts:        nil,
-		Keywords:    nil,
-		StringChars: nil,
-		Comment:     "",
-		HlStrings:   false,
-		HlNumbers:   false,
+		Name:             "java",
+		Exts:             []string{".java"},
+		Keywords:         []string{"abstract", "assert", "break", "case", "catch", "class", "const", "continue", "default", "do", "else", "enum", "extends", "final", "finally", "for", "if", "goto", "implements", "import", "instanceof", "interface", "native", "new", "package", "private", "protected", "public", "return", "static", "strictfp", "super", "switch", "synchronized", "this", "throw", "throws", "transient", "try", "volatile", "while", "_", "exports", "module", "non-sealed", "open", "opens", "permits", "provides", "record", "requires", "sealed", "to", "transitive", "uses", "var", "with", "yield"},
+		Types:            []string{"boolean", "byte", "char", "double", "float", "int", "long", "short", "void", "String", "Object"},
+		Constants:        []string{"true", "false", "null"},
+		Rules:            []RegexRule{{Pattern: `@[A-Za-z_][\w.]*`, Colour: "Decorators"}, {Pattern: `([A-Za-z_]\w*)\s*\(`, Colour: "Functions", Group: 1}},
+		StringChars:      []string{"\""},
+		CharChars:        []string{"'"},
+		Comment:          "//",
+		BlockComments:    []Delimiters{{Start: "/*", End: "*/"}},
+		MultilineStrings: []Delimiters{{Start: `"""`, End: `"""`}},
+		HlStrings:        true,
+		HlNumbers:        true,
 	},
 	{
-		Exts:        []string{".java"},
-		Keywords:    []string{"abstract", "assert", "boolean", "break", "byte", "case", "catch", "char", "class", "const", "continue", "default", "do", "double", "else", "enum", "extends", "final", "finally", "float", "for", "if", "goto", "implements", "import", "instanceof", "int", "interface", "long", "native", "new", "package", "private", "protected", "public", "return", "short", "static", "strictfp", "super", "switch", "synchronized", "this", "throw", "throws", "transient", "try", "void", "volatile", "while", "_", "exports", "module", "non-sealed", "open", "opens", "permits", "provides", "record", "requires", "sealed", "to", "transitive", "uses", "var", "with", "yield"},
-		StringChars: []string{"'", "\"", "`"},
-		Comment:     "//",
-		HlStrings:   true,
-		HlNumbers:   true,
+		Name:             "kotlin",
+		Exts:             []string{".kt"},
+		Keywords:         []string{"as", "as?", "break", "class", "continue", "do", "else", "for", "fun", "if", "in", "!in", "interface", "is", "!is", "object", "package", "return", "super", "this", "throw", "try", "typealias", "typeof", "val", "var", "when", "while", "by", "catch", "constructor", "delegate", "dynamic", "field", "file", "finally", "get", "import", "init", "param", "property", "receiver", "set", "setparam", "where", "actual", "abstract", "annotation", "companion", "const", "crossinline", "data", "enum", "expect", "external", "final", "infix", "inline", "inner", "internal", "lateinit", "noinline", "open", "operator", "out", "override", "private", "protected", "public", "reified", "sealed", "suspend", "tailrec", "vararg", "it"},
+		Types:            []string{"Any", "Boolean", "Byte", "Char", "Double", "Float", "Int", "Long", "Nothing", "Short", "String", "Unit"},
+		Constants:        []string{"true", "false", "null"},
+		Rules:            []RegexRule{{Pattern: `@[A-Za-z_][\w.]*`, Colour: "Decorators"}, {Pattern: `([A-Za-z_]\w*)\s*\(`, Colour: "Functions", Group: 1}},
+		StringChars:      []string{"\""},
+		CharChars:        []string{"'"},
+		Comment:          "//",
+		BlockComments:    []Delimiters{{Start: "/*", End: "*/"}},
+		MultilineStrings: []Delimiters{{Start: `"""`, End: `"""`}},
+		HlStrings:        true,
+		HlNumbers:        true,
 	},
 	{
-		Exts:        []string{".kt"},
-		Keywords:    []string{"as", "as?", "break", "class", "continue", "do", "else", "false", "for", "fun", "if", "in", "!in", "interface", "is", "!is", "null", "object", "package", "return", "super", "this", "throw", "true", "try", "typealias", "typeof", "val", "var", "when", "while", "by", "catch", "constructor", "delegate", "dynamic", "field", "file", "finally", "get", "import", "init", "param", "property", "receiver", "set", "setparam", "where", "actual", "abstract", "annotation", "companion", "const", "crossinline", "data", "enum", "expect", "external", "final", "infix", "inline", "inner", "internal", "lateinit", "noinline", "open", "operator", "out", "override", "private", "protected", "public", "reified", "sealed", "suspend", "tailrec", "vararg", "field", "it"},
-		StringChars: []string{"'", "\"", "`"},
-		Comment:     "//",
-		HlStrings:   true,
-		HlNumbers:   true,
+		Name:             "javascript",
+		Interpreters:     []string{"node"},
+		Exts:             []string{".js", ".jsx"},
+		Keywords:         []string{"break", "case", "catch", "class", "const", "continue", "debugger", "default", "delete", "do", "else", "export", "extends", "finally", "for", "function", "if", "import", "in", "instanceof", "new", "return", "super", "switch", "this", "throw", "try", "typeof", "var", "void", "while", "with", "yield", "let", "static", "enum", "await", "implements", "interface", "package", "private", "protected", "public"},
+		Constants:        []string{"null", "true", "false", "undefined", "NaN", "Infinity"},
+		Rules:            []RegexRule{{Pattern: `([A-Za-z_]\w*)\s*\(`, Colour: "Functions", Group: 1}},
+		StringChars:      []string{"'", "\"", "`"},
+		Comment:          "//",
+		BlockComments:    []Delimiters{{Start: "/*", End: "*/"}},
+		MultilineStrings: []Delimiters{{Start: "`", End: "`"}},
+		HlStrings:        true,
+		HlNumbers:        true,
 	},
 	{
-		Exts:        []string{".js", ".jsx"},
-		Keywords:    []string{"break", "case", "catch", "class", "const", "continue", "debugger", "default", "delete", "do", "else", "export", "extends", "finally", "for", "function", "if", "import", "in", "instanceof", "new", "return", "super", "switch", "this", "throw", "try", "typeof", "var", "void", "while", "with", "yield", "let", "static", "enum", "await", "implements", "interface", "package", "private", "protected", "public", "null", "true", "false"},
-		StringChars: []string{"'", "\"", "`"},
-		Comment:     "//",
-		HlStrings:   true,
-		HlNumbers:   true,
+		Name:             "go",
+		Exts:             []string{".go"},
+		Keywords:         []string{"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch", "type", "var"},
+		Types:            []string{"any", "bool", "byte", "complex64", "complex128", "error", "float32", "float64", "int", "int8", "int16", "int32", "int64", "rune", "string", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr"},
+		Builtins:         []string{"append", "cap", "close", "complex", "copy", "delete", "imag", "len", "make", "new", "panic", "print", "println", "real", "recover"},
+		Constants:        []string{"true", "false", "iota", "nil"},
+		Rules:            []RegexRule{{Pattern: `([A-Za-z_]\w*)\s*\(`, Colour: "Functions", Group: 1}},
+		StringChars:      []string{"\""},
+		CharChars:        []string{"'"},
+		Comment:          "//",
+		BlockComments:    []Delimiters{{Start: "/*", End: "*/"}},
+		MultilineStrings: []Delimiters{{Start: "`", End: "`", Raw: true}},
+		NumberFormats:    []string{"hex", "binary", "octal", "float"},
+		HlStrings:        true,
+		HlNumbers:        true,
 	},
 	{
-		Exts:        []string{".go"},
-		Keywords:    []string{"bool", "uint", "import", "package", "const", "var", "func", "map", "string", "byte", "struct", "int", "any", "error", "type", "continue", "break", "append", "if", "len", "return", "else"},
-		StringChars: []string{"'", "\"", "`"},
-		Comment:     "//",
-		HlStrings:   true,
-		HlNumbers:   true,
-	},
-	{
-		Exts:        []string{".py"},
-		Keywords:    []string{"False", "None", "True", "and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global", "if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return", "try", "while", "with", "yield"},
-		StringChars: []string{"'", "\"", "`"},
-		Comment:     "#",
-		HlStrings:   true,
-		HlNumbers:   true,
+		Name:             "python",
+		Interpreters:     []string{"python"},
+		Exts:             []string{".py"},
+		Keywords:         []string{"and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global", "if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return", "try", "while", "with", "yield"},
+		Builtins:         []string{"abs", "all", "any", "bool", "bytes", "dict", "enumerate", "filter", "float", "int", "isinstance", "len", "list", "map", "max", "min", "object", "open", "print", "range", "repr", "set", "sorted", "str", "sum", "super", "tuple", "type", "zip"},
+		Constants:        []string{"False", "None", "True"},
+		Rules:            []RegexRule{{Pattern: `@[A-Za-z_][\w.]*`, Colour: "Decorators"}, {Pattern: `([A-Za-z_]\w*)\s*\(`, Colour: "Functions", Group: 1}},
+		StringChars:      []string{"'", "\""},
+		Comment:          "#",
+		MultilineStrings: []Delimiters{{Start: `"""`, End: `"""`}, {Start: "'''", End: "'''"}},
+		HlStrings:        true,
+		HlNumbers:        true,
 	},
 	{
+		Name:      "xit",
 		Exts:      []string{".xit"},
 		Keywords:  []string{"[ ]", "[x]", "[@]", "[~]"},
 		Comment:   "#", // These are technically Tags. Current Gram does not support the semantics of xit entirely.
@@ -57,18 +85,48 @@ var builtinLanguageSyntaxs = []LanguageSyntax{
 		HlNumbers: true,
 	},
 	{
-		Exts:        []string{".sh"},
-		Keywords:    []string{"if", "fi", "elif", "case", "esac", "then"},
-		StringChars: []string{"'", "\"", "`"},
+		Name:         "sh",
+		Filenames:    []string{".bashrc", ".bash_profile", ".zshrc", ".profile"},
+		Interpreters: []string{"sh", "bash", "zsh", "dash"},
+		Exts:         []string{".sh"},
+		Keywords:     []string{"if", "fi", "elif", "case", "esac", "then"},
+		StringChars:  []string{"'", "\"", "`"},
+		Comment:      "#",
+		HlStrings:    true,
+		HlNumbers:    true,
+	},
+	{
+		Name:             "typescript",
+		Interpreters:     []string{"deno", "ts-node"},
+		Exts:             []string{".ts", ".tsx"},
+		Keywords:         []string{"break", "case", "catch", "class", "const", "continue", "debugger", "default", "delete", "do", "else", "enum", "export", "extends", "finally", "for", "function", "if", "import", "in", "instanceof", "new", "return", "super", "switch", "this", "throw", "try", "typeof", "var", "void", "while", "with", "let", "interface", "type", "implements", "private", "protected", "public", "readonly", "as"},
+		Types:            []string{"any", "boolean", "never", "number", "object", "string", "symbol", "unknown", "void"},
+		Constants:        []string{"null", "true", "false", "undefined"},
+		Rules:            []RegexRule{{Pattern: `([A-Za-z_]\w*)\s*\(`, Colour: "Functions", Group: 1}},
+		StringChars:      []string{"'", "\"", "`"},
+		Comment:          "//",
+		BlockComments:    []Delimiters{{Start: "/*", End: "*/"}},
+		MultilineStrings: []Delimiters{{Start: "`", End: "`"}},
+		HlStrings:        true,
+		HlNumbers:        true,
+	},
+	{
+		Name:        "make",
+		Exts:        []string{".mk"},
+		Filenames:   []string{"Makefile", "makefile", "GNUmakefile"},
+		Keywords:    []string{"ifeq", "ifneq", "ifdef", "ifndef", "else", "endif", "include", "define", "endef", "export", "override"},
+		StringChars: []string{"'", "\""},
 		Comment:     "#",
 		HlStrings:   true,
-		HlNumbers:   true,
+		HlNumbers:   false,
 	},
 	{
-		Exts:        []string{".ts", ".tsx"},
-		Keywords:    []string{"break", "case", "catch", "class", "const", "continue", "debugger", "default", "delete", "do", "else", "enum", "export", "extends", "false", "finally", "for", "function", "If", "import", "in", "istanceOf", "new", "null", "return", "super", "switch", "this", "throw", "true", "try", "typeOf", "var", "void", "while", "with"},
-		StringChars: []string{"'", "\"", "`"},
-		Comment:     "//",
+		Name:        "dockerfile",
+		Exts:        []string{".dockerfile"},
+		Filenames:   []string{"Dockerfile", "Containerfile"},
+		Keywords:    []string{"FROM", "AS", "RUN", "CMD", "LABEL", "EXPOSE", "ENV", "ADD", "COPY", "ENTRYPOINT", "VOLUME", "USER", "WORKDIR", "ARG", "ONBUILD", "STOPSIGNAL", "HEALTHCHECK", "SHELL"},
+		StringChars: []string{"'", "\""},
+		Comment:     "#",
 		HlStrings:   true,
 		HlNumbers:   true,
 	},
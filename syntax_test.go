@@ -1,10 +1,32 @@
 package main
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestLoad(t *testing.T) {
-	syntaxes := LoadSyntaxesFromFile("syntax.json")
-	if len(syntaxes) == 0 {
+	syntaxes, err := LoadSyntaxesFromFile("syntax.json")
+	if err != nil || len(syntaxes) == 0 {
 		t.Errorf("Failed to load syntaxes from 'syntax.json' or no syntaxes in file.")
 	}
 }
+
+// TestLoadMatchesBuiltins, so that copying the shipped syntax.json to the config directory doesn't change highlighting.
+func TestLoadMatchesBuiltins(t *testing.T) {
+	syntaxes, err := LoadSyntaxesFromFile("syntax.json")
+	if err != nil {
+		t.Fatalf("Failed to load syntaxes from 'syntax.json': %s", err)
+	}
+	for _, l := range syntaxes {
+		t.Run(l.Name, func(t *testing.T) {
+			builtin, exists := FindLanguageSyntax(builtinLanguageSyntaxs, l.Name)
+			if !exists {
+				t.Fatalf("No builtin syntax named %q", l.Name)
+			}
+			if !reflect.DeepEqual(l, builtin) {
+				t.Errorf("syntax.json differs from the builtin.\nsyntax.json: %+v\nbuiltin:     %+v", l, builtin)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"strings"
+)
+
+// Tokenize row x in a single left-to-right pass, starting in lexer state in. Returns the colour of each byte of x and
+// the lexer state at the end of x. Comments, strings (with escapes), numbers and keywords are mutually exclusive, so
+// the first token to start at an index owns it.
+func (s *Syntax) Tokenize(x string, in HlState) ([]Colour, HlState) {
+	hl := make([]Colour, len(x))
+	i := 0
+
+	if in.Kind != HL_NORMAL {
+		end, closed := s.regionEnd(x, 0, in)
+		fillColour(hl, 0, end, s.regionColour(in))
+		if !closed {
+			return hl, in
+		}
+		i = end
+	}
+
+	for i < len(x) {
+		rest := x[i:]
+
+		// Line comments, with TODOs highlighted after the comment characters.
+		if len(s.l.Comment) > 0 && strings.HasPrefix(rest, s.l.Comment) {
+			fillColour(hl, i, len(x), s.c.Comments)
+			if strings.HasPrefix(rest[len(s.l.Comment):], " TODO") {
+				fillColour(hl, i+len(s.l.Comment), len(x), s.c.Todos)
+			}
+			return hl, HlState{}
+		}
+
+		// Regions that may span multiple rows.
+		if state, d, ok := s.regionStart(rest); ok {
+			end, closed := s.regionEnd(x, i+len(d.Start), state)
+			fillColour(hl, i, end, s.regionColour(state))
+			if !closed {
+				return hl, state
+			}
+			i = end
+			continue
+		}
+
+		// Single line strings and character literals. Unterminated strings continue to the end of the row.
+		if q, c, ok := s.quoteStart(rest); ok {
+			end, _ := stringEnd(x, i+len(q), q, false)
+			if s.l.HlStrings {
+				fillColour(hl, i, end, c)
+			}
+			i = end
+			continue
+		}
+
+		if !isWordStart(x, i) {
+			i++
+			continue
+		}
+
+		if n := s.numberLen(rest); n > 0 {
+			if s.l.HlNumbers {
+				fillColour(hl, i, i+n, s.c.Numbers)
+			}
+			i += n
+			continue
+		}
+
+		if k, c := s.keywordAt(x, i); len(k) > 0 {
+			fillColour(hl, i, i+len(k), c)
+			i += len(k)
+			continue
+		}
+
+		if start, end, c, ok := s.ruleAt(rest); ok {
+			fillColour(hl, i+start, i+end, c)
+			i += end
+			continue
+		}
+
+		// Skip the remainder of a plain identifier, so its digits aren't treated as numbers.
+		i++
+		for i < len(x) && isIdentChar(x[i]) && isIdentChar(x[i-1]) {
+			i++
+		}
+	}
+	return hl, HlState{}
+}
+
+// regionStart returns the state and delimiters of a block comment or multi-line string starting at the beginning of x.
+func (s *Syntax) regionStart(x string) (HlState, Delimiters, bool) {
+	for k, d := range s.l.BlockComments {
+		if len(d.Start) > 0 && strings.HasPrefix(x, d.Start) {
+			return HlState{Kind: HL_BLOCK_COMMENT, Delim: k}, d, true
+		}
+	}
+	for k, d := range s.l.MultilineStrings {
+		if len(d.Start) > 0 && strings.HasPrefix(x, d.Start) {
+			return HlState{Kind: HL_MULTILINE_STRING, Delim: k}, d, true
+		}
+	}
+	return HlState{}, Delimiters{}, false
+}
+
+// regionEnd finds the end, exclusive, of the region open in state, searching x from index i. Returns len(x) and false
+// if the region is not closed within x.
+func (s *Syntax) regionEnd(x string, i int, state HlState) (int, bool) {
+	d := s.regionDelimiters(state)
+	if len(d.End) == 0 {
+		return len(x), false
+	}
+	if state.Kind == HL_MULTILINE_STRING {
+		return stringEnd(x, i, d.End, d.Raw)
+	}
+
+	j := strings.Index(x[i:], d.End)
+	if j == -1 {
+		return len(x), false
+	}
+	return i + j + len(d.End), true
+}
+
+func (s *Syntax) regionDelimiters(state HlState) Delimiters {
+	if state.Kind == HL_BLOCK_COMMENT && state.Delim < len(s.l.BlockComments) {
+		return s.l.BlockComments[state.Delim]
+	}
+	if state.Kind == HL_MULTILINE_STRING && state.Delim < len(s.l.MultilineStrings) {
+		return s.l.MultilineStrings[state.Delim]
+	}
+	return Delimiters{}
+}
+
+// regionColour of a region in state. Multi-line strings are left uncoloured unless the language highlights strings.
+func (s *Syntax) regionColour(state HlState) Colour {
+	if state.Kind == HL_BLOCK_COMMENT {
+		return s.c.Comments
+	}
+	if !s.l.HlStrings {
+		return ""
+	}
+	return s.c.Strings
+}
+
+// quoteStart returns the quote, and its colour, that starts a string or character literal at the beginning of x.
+func (s *Syntax) quoteStart(x string) (string, Colour, bool) {
+	for _, q := range s.l.StringChars {
+		if len(q) > 0 && strings.HasPrefix(x, q) {
+			return q, s.c.Strings, true
+		}
+	}
+	for _, q := range s.l.CharChars {
+		if len(q) > 0 && strings.HasPrefix(x, q) {
+			return q, s.c.Chars, true
+		}
+	}
+	return "", "", false
+}
+
+// stringEnd returns the index after the closing quote q of a string whose contents start at i. Backslash escapes the
+// following character unless raw. Returns len(x) and false if the string is unterminated.
+func stringEnd(x string, i int, q string, raw bool) (int, bool) {
+	for i < len(x) {
+		if x[i] == '\\' && !raw {
+			i += 2
+			continue
+		}
+		if strings.HasPrefix(x[i:], q) {
+			return i + len(q), true
+		}
+		i++
+	}
+	return len(x), false
+}
+
+// keywordAt returns the longest word, from any keyword group, starting at index i of x that is a standalone word, and
+// the colour of its group.
+func (s *Syntax) keywordAt(x string, i int) (string, Colour) {
+	match, colour := "", Colour("")
+	groups := []struct {
+		words []string
+		c     Colour
+	}{
+		{s.l.Keywords, s.c.Keyword},
+		{s.l.Types, s.c.Types},
+		{s.l.Builtins, s.c.Builtins},
+		{s.l.Constants, s.c.Constants},
+	}
+	for _, g := range groups {
+		for _, k := range g.words {
+			if len(k) > len(match) && strings.HasPrefix(x[i:], k) && isWord(x, i, i+len(k)) {
+				match, colour = k, g.c
+			}
+		}
+	}
+	return match, colour
+}
+
+// ruleAt returns the first regex rule matching the start of x, with the start and end of the text to highlight.
+func (s *Syntax) ruleAt(x string) (int, int, Colour, bool) {
+	for _, r := range s.rules {
+		loc := r.re.FindStringSubmatchIndex(x)
+		if loc == nil || loc[2*r.group] < 0 || loc[2*r.group+1] == 0 {
+			continue
+		}
+		return loc[2*r.group], loc[2*r.group+1], r.c, true
+	}
+	return 0, 0, "", false
+}
+
+// numberLen returns the length of the number at the start of x, or 0 if x doesn't start with a number supported by
+// the syntax's number formats.
+func (s *Syntax) numberLen(x string) int {
+	if len(x) == 0 || !isDigit(x[0]) {
+		return 0
+	}
+
+	i := 0
+	if len(x) > 2 && x[0] == '0' {
+		switch x[1] {
+		case 'x', 'X':
+			if s.hasNumberFormat("hex") {
+				i = 2 + digitsLen(x[2:], isHexDigit)
+			}
+		case 'b', 'B':
+			if s.hasNumberFormat("binary") {
+				i = 2 + digitsLen(x[2:], func(c byte) bool { return c == '0' || c == '1' })
+			}
+		case 'o', 'O':
+			if s.hasNumberFormat("octal") {
+				i = 2 + digitsLen(x[2:], func(c byte) bool { return c >= '0' && c <= '7' })
+			}
+		}
+		if i == 2 {
+			i = 0 // Prefix without digits
+		}
+	}
+
+	if i == 0 {
+		i = digitsLen(x, isDigit)
+		if s.hasNumberFormat("float") {
+			if i+1 < len(x) && x[i] == '.' && isDigit(x[i+1]) {
+				i += 1 + digitsLen(x[i+1:], isDigit)
+			}
+			if i+1 < len(x) && (x[i] == 'e' || x[i] == 'E') {
+				j := i + 1
+				if x[j] == '+' || x[j] == '-' {
+					j++
+				}
+				if n := digitsLen(x[j:], isDigit); n > 0 {
+					i = j + n
+				}
+			}
+		}
+	}
+
+	// Numbers followed by letters are identifiers (or unsupported formats), not numbers.
+	if i < len(x) && isIdentChar(x[i]) {
+		return 0
+	}
+	return i
+}
+
+func (s *Syntax) hasNumberFormat(f string) bool {
+	if len(s.l.NumberFormats) == 0 {
+		return true
+	}
+	for _, x := range s.l.NumberFormats {
+		if x == f {
+			return true
+		}
+	}
+	return false
+}
+
+// digitsLen returns the length of the run of digits, or '_' separators, at the start of x.
+func digitsLen(x string, isDigit func(byte) bool) int {
+	i := 0
+	for i < len(x) && (isDigit(x[i]) || (i > 0 && x[i] == '_')) {
+		i++
+	}
+	return i
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// isWordStart returns true if index i of x is not preceded by an identifier character.
+func isWordStart(x string, i int) bool {
+	return i == 0 || !isIdentChar(x[i-1])
+}
+
+func isIdentChar(c byte) bool {
+	return isDigit(c) || c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func fillColour(hl []Colour, start, end int, c Colour) {
+	for i := start; i < end && i < len(hl); i++ {
+		hl[i] = c
+	}
+}
@@ -91,10 +91,20 @@ func OpenOrCreate(filename string) ([]Row, error) {
 		}
 	}
 
+	return ReadRows(filename)
+}
+
+// ReadRows of an existing file.
+func ReadRows(filename string) ([]Row, error) {
 	raw, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return []Row{}, err
 	}
+	return ParseRows(raw), nil
+}
+
+// ParseRows from the raw contents of a file.
+func ParseRows(raw []byte) []Row {
 	file := strings.ReplaceAll(string(raw), "\r", "\n")
 	rawRows := strings.Split(file, "\n")
 	rows := make([]Row, len(rawRows))
@@ -103,5 +113,5 @@ func OpenOrCreate(filename string) ([]Row, error) {
 		rows[i] = ConstructRow(s)
 	}
 
-	return rows, nil
+	return rows
 }